@@ -1,19 +1,21 @@
 package commands
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"scrago/downloader"
 	"scrago/engine"
-	"scrago/middleware"
-	"scrago/pipeline"
 	"scrago/settings"
-	"scrago/spider"
-	"scrago/spiders"
+	"scrago/spiderloader"
+	_ "scrago/spiders" // 注册内置爬虫
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -21,12 +23,18 @@ import (
 func CrawlCommand(args []string) {
 	if len(args) == 0 {
 		fmt.Println("❌ 错误: 请指定要运行的爬虫名称")
-		fmt.Println("用法: scrapy crawl <spider_name>")
-		fmt.Println("示例: scrapy crawl douban")
+		fmt.Println("用法: scrago crawl <spider_name>")
+		fmt.Println("示例: scrago crawl douban")
 		return
 	}
 
 	spiderName := args[0]
+	info, ok := spiderloader.Lookup(spiderName)
+	if !ok {
+		fmt.Printf("❌ 未知的爬虫: %s\n", spiderName)
+		fmt.Println("💡 使用 'scrago list' 查看可用的爬虫")
+		os.Exit(1)
+	}
 	
 	// 解析命令行参数
 	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
@@ -43,37 +51,57 @@ func CrawlCommand(args []string) {
 
 	fmt.Printf("🚀 启动爬虫: %s\n", spiderName)
 
-	// 加载配置
-	config := loadSettings(*configFile, *settingsFlag)
+	// 加载配置：配置文件 → 爬虫自定义设置 → 命令行设置
+	config := loadSettings(*configFile)
+	if info.CustomSettings != nil {
+		info.CustomSettings(config)
+	}
+	if *settingsFlag != "" {
+		applyCommandLineSettings(config, *settingsFlag)
+	}
 	
 	// 设置输出文件
 	if *outputFile != "" {
 		setOutputFile(config, *outputFile)
 	}
 
+	// 第一次收到 SIGINT/SIGTERM 时优雅关闭，第二次强制退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	
+	go func() {
+		sig := <-sigChan
+		fmt.Printf("\n🛑 收到信号 %v，正在优雅关闭（再次按 Ctrl-C 强制退出）...\n", sig)
+		cancel()
+		
+		sig = <-sigChan
+		fmt.Printf("\n💥 再次收到信号 %v，强制退出\n", sig)
+		os.Exit(130)
+	}()
+
 	// 创建并运行爬虫
-	if err := runSpider(spiderName, config); err != nil {
+	if err := runSpider(ctx, info.Name, config); err != nil {
 		fmt.Printf("❌ 爬虫运行失败: %v\n", err)
 		os.Exit(1)
 	}
 }
 
 // loadSettings 加载配置
-func loadSettings(configFile, settingsFlag string) *settings.Settings {
+func loadSettings(configFile string) *settings.Settings {
 	var config *settings.Settings
 
 	// 如果指定了配置文件，尝试加载
 	if configFile != "" {
-		if data, err := os.ReadFile(configFile); err == nil {
-			config = &settings.Settings{}
-			if err := json.Unmarshal(data, config); err != nil {
-				fmt.Printf("⚠️  配置文件解析失败，使用默认配置: %v\n", err)
-				config = settings.DefaultSettings()
-			}
-		} else {
-			fmt.Printf("⚠️  配置文件读取失败，使用默认配置: %v\n", err)
-			config = settings.DefaultSettings()
+		loaded, err := settings.LoadFromFile(configFile)
+		if err != nil {
+			fmt.Printf("⚠️  配置文件加载失败，使用默认配置: %v\n", err)
+			loaded = settings.DefaultSettings()
 		}
+		config = loaded
 	} else {
 		// 尝试加载默认配置文件
 		defaultConfigPaths := []string{
@@ -84,22 +112,17 @@ func loadSettings(configFile, settingsFlag string) *settings.Settings {
 		
 		config = settings.DefaultSettings()
 		for _, path := range defaultConfigPaths {
-			if data, err := os.ReadFile(path); err == nil {
-				tempConfig := &settings.Settings{}
-				if err := json.Unmarshal(data, tempConfig); err == nil {
-					config = tempConfig
-					fmt.Printf("📄 加载配置文件: %s\n", path)
-					break
-				}
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if tempConfig, err := settings.LoadFromFile(path); err == nil {
+				config = tempConfig
+				fmt.Printf("📄 加载配置文件: %s\n", path)
+				break
 			}
 		}
 	}
 
-	// 应用命令行设置
-	if settingsFlag != "" {
-		applyCommandLineSettings(config, settingsFlag)
-	}
-
 	return config
 }
 
@@ -117,6 +140,16 @@ func applyCommandLineSettings(config *settings.Settings, settingsFlag string) {
 					config.ConcurrentRequests = val
 					fmt.Printf("⚙️  设置并发数: %d\n", val)
 				}
+			case "CONCURRENT_REQUESTS_PER_DOMAIN":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.ConcurrentRequestsPerDomain = val
+					fmt.Printf("⚙️  设置每域名并发数: %d\n", val)
+				}
+			case "CONCURRENT_REQUESTS_PER_IP":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.ConcurrentRequestsPerIP = val
+					fmt.Printf("⚙️  设置每IP并发数: %d\n", val)
+				}
 			case "DOWNLOAD_DELAY":
 				if val, err := strconv.ParseFloat(value, 64); err == nil {
 					config.DownloadDelay = time.Duration(val * float64(time.Second))
@@ -125,6 +158,173 @@ func applyCommandLineSettings(config *settings.Settings, settingsFlag string) {
 			case "USER_AGENT":
 				config.UserAgent = value
 				fmt.Printf("⚙️  设置User-Agent: %s\n", value)
+			case "USER_AGENT_LIST":
+				config.UserAgentList = parseListSetting(value)
+				fmt.Printf("⚙️  设置User-Agent列表: %d 个\n", len(config.UserAgentList))
+			case "PROXY_LIST":
+				config.ProxyList = parseListSetting(value)
+				fmt.Printf("⚙️  设置代理列表: %d 个\n", len(config.ProxyList))
+			case "SHUTDOWN_TIMEOUT":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.ShutdownTimeout = time.Duration(val * float64(time.Second))
+					fmt.Printf("⚙️  设置关闭等待时间: %v\n", config.ShutdownTimeout)
+				}
+			case "CLOSESPIDER_ITEMCOUNT":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.CloseSpiderItemCount = val
+					fmt.Printf("⚙️  设置抓取数据项数上限: %d\n", val)
+				}
+			case "CLOSESPIDER_PAGECOUNT":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.CloseSpiderPageCount = val
+					fmt.Printf("⚙️  设置下载页面数上限: %d\n", val)
+				}
+			case "CLOSESPIDER_ERRORCOUNT":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.CloseSpiderErrorCount = val
+					fmt.Printf("⚙️  设置爬虫异常数上限: %d\n", val)
+				}
+			case "CLOSESPIDER_TIMEOUT":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.CloseSpiderTimeout = time.Duration(val * float64(time.Second))
+					fmt.Printf("⚙️  设置最长运行时间: %v\n", config.CloseSpiderTimeout)
+				}
+			case "ROBOTSTXT_OBEY":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.RobotstxtObey = val
+					fmt.Printf("⚙️  设置遵守robots.txt: %v\n", val)
+				}
+			case "ROBOTSTXT_USER_AGENT":
+				config.RobotstxtUserAgent = value
+				fmt.Printf("⚙️  设置robots.txt User-Agent: %s\n", value)
+			case "ROBOTSTXT_FAILURE_POLICY":
+				config.RobotstxtFailurePolicy = value
+				fmt.Printf("⚙️  设置robots.txt获取失败策略: %s\n", value)
+			case "AUTOTHROTTLE_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.AutoThrottleEnabled = val
+					fmt.Printf("⚙️  设置自动限速: %v\n", val)
+				}
+			case "AUTOTHROTTLE_TARGET_CONCURRENCY":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.AutoThrottleTargetConcurrency = val
+					fmt.Printf("⚙️  设置自动限速目标并发: %.1f\n", val)
+				}
+			case "AUTOTHROTTLE_START_DELAY":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.AutoThrottleStartDelay = time.Duration(val * float64(time.Second))
+					fmt.Printf("⚙️  设置自动限速初始延迟: %v\n", config.AutoThrottleStartDelay)
+				}
+			case "AUTOTHROTTLE_MAX_DELAY":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.AutoThrottleMaxDelay = time.Duration(val * float64(time.Second))
+					fmt.Printf("⚙️  设置自动限速最大延迟: %v\n", config.AutoThrottleMaxDelay)
+				}
+			case "AUTOTHROTTLE_DEBUG":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.AutoThrottleDebug = val
+					fmt.Printf("⚙️  设置自动限速调试: %v\n", val)
+				}
+			case "URLLENGTH_LIMIT":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.URLLengthLimit = val
+					fmt.Printf("⚙️  设置URL最大长度: %d\n", val)
+				}
+			case "DEPTH_LIMIT":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.DepthLimit = val
+					fmt.Printf("⚙️  设置最大爬取深度: %d\n", val)
+				}
+			case "DEPTH_PRIORITY":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.DepthPriority = val
+					fmt.Printf("⚙️  设置深度优先级: %d\n", val)
+				}
+			case "RETRY_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.RetryEnabled = val
+					fmt.Printf("⚙️  设置重试开关: %v\n", val)
+				}
+			case "RETRY_TIMES":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.RetryTimes = val
+					fmt.Printf("⚙️  设置重试次数: %d\n", val)
+				}
+			case "DUPEFILTER_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.DupeFilterEnabled = val
+					fmt.Printf("⚙️  设置去重开关: %v\n", val)
+				}
+			case "DUPEFILTER_BACKEND":
+				config.DupeFilterBackend = value
+				fmt.Printf("⚙️  设置去重后端: %s\n", value)
+			case "DUPEFILTER_PATH":
+				config.DupeFilterPath = value
+				fmt.Printf("⚙️  设置去重文件: %s\n", value)
+			case "SCHEDULER":
+				config.Scheduler = value
+				fmt.Printf("⚙️  设置调度器: %s\n", value)
+			case "SCHEDULER_DISK_QUEUE":
+				config.SchedulerDiskQueue = value
+				fmt.Printf("⚙️  设置磁盘队列顺序: %s\n", value)
+			case "JOBDIR":
+				config.JobDir = value
+				fmt.Printf("⚙️  设置持久化目录: %s\n", value)
+			case "LOGSTATS_INTERVAL":
+				if val, err := strconv.ParseFloat(value, 64); err == nil {
+					config.LogStatsInterval = time.Duration(val * float64(time.Second))
+					fmt.Printf("⚙️  设置爬取速度日志间隔: %v\n", config.LogStatsInterval)
+				}
+			case "MEMUSAGE_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.MemUsageEnabled = val
+					fmt.Printf("⚙️  设置内存监控: %v\n", val)
+				}
+			case "MEMUSAGE_LIMIT_MB":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.MemUsageLimitMB = val
+					fmt.Printf("⚙️  设置内存上限: %d MB\n", val)
+				}
+			case "MEMUSAGE_WARNING_MB":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.MemUsageWarningMB = val
+					fmt.Printf("⚙️  设置内存警告值: %d MB\n", val)
+				}
+			case "CACHE_ENABLED", "HTTPCACHE_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.CacheEnabled = val
+					fmt.Printf("⚙️  设置HTTP缓存: %v\n", val)
+				}
+			case "CACHE_EXPIRE":
+				if val, err := strconv.Atoi(value); err == nil {
+					config.CacheExpire = val
+					fmt.Printf("⚙️  设置缓存有效期: %d 秒\n", val)
+				}
+			case "CACHE_DIR":
+				config.CacheDir = value
+				fmt.Printf("⚙️  设置缓存目录: %s\n", value)
+			case "CACHE_POLICY":
+				config.CachePolicy = value
+				fmt.Printf("⚙️  设置缓存策略: %s\n", value)
+			case "CACHE_STORAGE":
+				config.CacheStorage = value
+				fmt.Printf("⚙️  设置缓存存储: %s\n", value)
+			case "COOKIES_ENABLED":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.CookiesEnabled = val
+					fmt.Printf("⚙️  设置Cookie: %v\n", val)
+				}
+			case "COOKIES_DEBUG":
+				if val, err := strconv.ParseBool(value); err == nil {
+					config.CookiesDebug = val
+					fmt.Printf("⚙️  设置Cookie调试: %v\n", val)
+				}
+			case "COOKIES_FILE":
+				config.CookiesFile = value
+				fmt.Printf("⚙️  设置Cookie文件: %s\n", value)
+			case "STATS_FILE":
+				config.StatsFile = value
+				fmt.Printf("⚙️  设置统计信息文件: %s\n", value)
 			case "RANDOMIZE_DOWNLOAD_DELAY":
 				if val, err := strconv.ParseBool(value); err == nil {
 					config.RandomizeDownloadDelay = val
@@ -137,6 +337,25 @@ func applyCommandLineSettings(config *settings.Settings, settingsFlag string) {
 	}
 }
 
+// parseListSetting 解析列表设置：值为文件时每行一项（忽略空行和#注释），否则按|分隔
+func parseListSetting(value string) []string {
+	var items []string
+	if data, err := os.ReadFile(value); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				items = append(items, line)
+			}
+		}
+		return items
+	}
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // setOutputFile 设置输出文件
 func setOutputFile(config *settings.Settings, outputFile string) {
 	// 确保输出目录存在
@@ -171,45 +390,64 @@ func setOutputFile(config *settings.Settings, outputFile string) {
 }
 
 // runSpider 运行指定的爬虫
-func runSpider(spiderName string, config *settings.Settings) error {
+func runSpider(ctx context.Context, spiderName string, config *settings.Settings) error {
 	// 创建引擎
 	eng := engine.NewEngine()
 	
-	// 添加中间件
-	userAgents := []string{
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	// 按域名（或IP）限制并发，每个下载槽单独计算下载延迟
+	slots := downloader.NewSlotManager(config.ConcurrentRequestsPerDomain, config.ConcurrentRequestsPerIP,
+		config.DownloadDelay, config.RandomizeDownloadDelay)
+	for key, slot := range config.DownloadSlots {
+		slots.Configure(key, slot.Concurrency, slot.Delay)
 	}
-	eng.AddMiddleware(middleware.NewUserAgentMiddleware(userAgents, true))
-	eng.AddMiddleware(middleware.NewDelayMiddleware(config.DownloadDelay, config.RandomizeDownloadDelay))
+	eng.SetDownloadSlots(slots)
 	
-	// 添加管道
-	if len(config.FeedsExport) > 0 {
-		for _, feedConfig := range config.FeedsExport {
-			jsonPipeline := pipeline.NewJSONPipeline(feedConfig.URI)
-			eng.AddPipeline(jsonPipeline)
-		}
-	} else {
-		// 默认输出到文件
+	// 未指定输出时默认输出到文件
+	if len(config.FeedsExport) == 0 {
 		defaultOutput := fmt.Sprintf("%s_output.json", spiderName)
-		jsonPipeline := pipeline.NewJSONPipeline(defaultOutput)
-		eng.AddPipeline(jsonPipeline)
+		config.FeedsExport = map[string]settings.FeedExportSettings{
+			defaultOutput: {Format: "json", URI: defaultOutput, Encoding: "utf-8"},
+		}
 		fmt.Printf("📁 使用默认输出文件: %s\n", defaultOutput)
 	}
+	
+	// 按DOWNLOADER_MIDDLEWARES、SPIDER_MIDDLEWARES、ITEM_PIPELINES和EXTENSIONS创建组件
+	if err := eng.ApplySettings(config); err != nil {
+		return fmt.Errorf("创建组件失败: %w", err)
+	}
+	if config.RobotstxtObey {
+		fmt.Println("🤖 遵守robots.txt")
+	}
 
 	// 根据爬虫名称创建爬虫实例
-	var spider spider.Spider
-	switch strings.ToLower(spiderName) {
-	case "douban", "douban_movie":
-		spider = spiders.NewDoubanMovieSpider(config)
-	default:
-		return fmt.Errorf("未知的爬虫: %s", spiderName)
+	spider, err := spiderloader.Load(spiderName, config)
+	if err != nil {
+		return err
 	}
 
 	// 设置引擎配置
 	eng.SetConcurrency(config.ConcurrentRequests)
+	
+	if config.ShutdownTimeout > 0 {
+		eng.SetShutdownTimeout(config.ShutdownTimeout)
+	}
+	if config.StatsFile != "" {
+		eng.SetStatsFile(config.StatsFile)
+	}
+	eng.SetCloseSpider(engine.CloseSpiderConditions{
+		ItemCount:  int64(config.CloseSpiderItemCount),
+		PageCount:  int64(config.CloseSpiderPageCount),
+		ErrorCount: int64(config.CloseSpiderErrorCount),
+		Timeout:    config.CloseSpiderTimeout,
+	})
+	
+	// 调度器与去重过滤器已由ApplySettings按SCHEDULER创建，指定JOBDIR时使用可恢复的磁盘队列
+	if config.JobDir != "" {
+		fmt.Printf("💾 持久化目录: %s\n", config.JobDir)
+	}
 
 	fmt.Printf("⚙️  并发数: %d\n", config.ConcurrentRequests)
+	fmt.Printf("🌐 每域名并发数: %d\n", config.ConcurrentRequestsPerDomain)
 	fmt.Printf("⏱️  下载延迟: %v\n", config.DownloadDelay)
 	fmt.Printf("🎲 随机延迟: %v\n", config.RandomizeDownloadDelay)
 	fmt.Println("🕷️  开始爬取...")
@@ -218,7 +456,12 @@ func runSpider(spiderName string, config *settings.Settings) error {
 	startTime := time.Now()
 
 	// 运行爬虫
-	eng.Run(spider)
+	var closed *engine.SpiderClosedError
+	if err := eng.Run(ctx, spider); errors.As(err, &closed) {
+		fmt.Printf("\n🛑 爬虫已提前关闭: %s\n", closed.Reason)
+	} else if err != nil {
+		return err
+	}
 
 	// 显示统计信息
 	duration := time.Since(startTime)
@@ -86,74 +86,82 @@ func isValidSpiderName(name string) bool {
 	return true
 }
 
-// generateSpiderCode 生成爬虫代码
+// generateSpiderCode 生成爬虫代码，生成的爬虫在init()中注册，scrago crawl 和 scrago list 即可使用
 func generateSpiderCode(spiderName, domain string) string {
 	structName := strings.Title(spiderName) + "Spider"
 	startURL := fmt.Sprintf("https://%s", domain)
-	
+
 	return fmt.Sprintf(`package spiders
 
 import (
 	"fmt"
 	"scrago/request"
 	"scrago/response"
-	"scrago/selector"
 	"scrago/settings"
 	"scrago/spider"
+	"scrago/spiderloader"
 	"strings"
 )
 
-// %sItem 数据结构
-type %sItem struct {
+func init() {
+	spiderloader.Register(spiderloader.Info{
+		Name:           "%[2]s",
+		Description:    "%[2]s 爬虫", // TODO: 填写爬虫描述
+		AllowedDomains: []string{"%[3]s"},
+		New: func(s *settings.Settings) spider.Spider {
+			return New%[1]s(s)
+		},
+	})
+}
+
+// %[1]sItem 数据结构
+type %[1]sItem struct {
 	Title string ` + "`" + `json:"title"` + "`" + `
 	URL   string ` + "`" + `json:"url"` + "`" + `
 	// TODO: 添加更多字段
 }
 
-// %s 爬虫
-type %s struct {
+// %[1]s 爬虫
+type %[1]s struct {
 	*spider.BaseSpider
 	settings *settings.Settings
 }
 
-// New%s 创建新的爬虫实例
-func New%s(settings *settings.Settings) *%s {
+// New%[1]s 创建新的爬虫实例
+func New%[1]s(settings *settings.Settings) *%[1]s {
 	startURLs := []string{
-		"%s",
+		"%[4]s",
 		// TODO: 添加更多起始URL
 	}
 
-	base := spider.NewBaseSpider("%s", startURLs)
+	base := spider.NewBaseSpiderWithDomains("%[2]s", startURLs, []string{"%[3]s"})
 
-	return &%s{
+	return &%[1]s{
 		BaseSpider: base,
 		settings:   settings,
 	}
 }
 
 // StartRequests 生成初始请求
-func (s *%s) StartRequests() []*request.Request {
-	var requests []*request.Request
-
-	for _, url := range s.StartURLs {
-		req := request.NewRequest("GET", url)
+func (s *%[1]s) StartRequests() []*request.Request {
+	requests := s.BaseSpider.StartRequests()
+	for _, req := range requests {
 		req.SetHeader("User-Agent", "Mozilla/5.0 (compatible; Go-Scrapy/1.0)")
 		req.SetMeta("callback", "parse")
-		requests = append(requests, req)
 	}
 
-	fmt.Printf("🚀 %s爬虫：生成了 %%d 个初始请求\n", len(requests))
+	fmt.Printf("🚀 %[2]s爬虫：生成了 %%d 个初始请求\n", len(requests))
 	return requests
 }
 
 // Parse 解析响应
-func (s *%s) Parse(resp *response.Response) []interface{} {
+func (s *%[1]s) Parse(resp *response.Response) []interface{} {
 	if resp.StatusCode != 200 {
 		fmt.Printf("❌ 请求失败，状态码: %%d, URL: %%s\n", resp.StatusCode, resp.URL)
 		return []interface{}{}
 	}
 
-	sel := selector.NewSelector(string(resp.Body))
+	sel := resp.Selector()
 	var results []interface{}
 
 	// TODO: 实现你的解析逻辑
@@ -161,7 +169,7 @@ func (s *%s) Parse(resp *response.Response) []interface{} {
 	links := sel.CSS("a").Attrs("href")
 	for _, link := range links {
 		if strings.HasPrefix(link, "http") {
-			item := &%sItem{
+			item := &%[1]sItem{
 				Title: "示例标题", // TODO: 提取实际标题
 				URL:   link,
 			}
@@ -172,5 +180,5 @@ func (s *%s) Parse(resp *response.Response) []interface{} {
 	fmt.Printf("📄 从 %%s 提取了 %%d 个项目\n", resp.URL, len(results))
 	return results
 }
-`, structName, structName, structName, structName, structName, structName, structName, startURL, spiderName, structName, structName, structName, structName)
-}
\ No newline at end of file
+`, structName, spiderName, domain, startURL)
+}
@@ -157,11 +157,10 @@ func generateScrapyConfig() string {
   "download_delay": 1.0,
   "randomize_download_delay": true,
   "downloader_middlewares": {
-    "UserAgentMiddleware": 100,
-    "DelayMiddleware": 200
+    "UserAgentMiddleware": 100
   },
   "item_pipelines": {
-    "JSONPipeline": 100
+    "FeedExportPipeline": 800
   },
   "feeds_export": {
     "output.json": {
@@ -181,49 +180,56 @@ func generateExampleSpider(projectName string) string {
 import (
 	"scrago/request"
 	"scrago/response"
-	"scrago/spider"
 	"scrago/settings"
+	"scrago/spider"
+	"scrago/spiderloader"
 )
 
-// %s 示例爬虫
-type %s struct {
+func init() {
+	spiderloader.Register(spiderloader.Info{
+		Name:        "example",
+		Description: "示例爬虫",
+		New: func(s *settings.Settings) spider.Spider {
+			return New%[1]s(s)
+		},
+	})
+}
+
+// %[1]s 示例爬虫
+type %[1]s struct {
 	*spider.BaseSpider
 	settings *settings.Settings
 }
 
-// New%s 创建新的爬虫实例
-func New%s(settings *settings.Settings) *%s {
+// New%[1]s 创建新的爬虫实例
+func New%[1]s(settings *settings.Settings) *%[1]s {
 	startURLs := []string{
 		"https://example.com",
 	}
 
-	base := spider.NewBaseSpider("example", startURLs)
+	base := spider.NewBaseSpiderWithDomains("example", startURLs, []string{"example.com"})
 
-	return &%s{
+	return &%[1]s{
 		BaseSpider: base,
 		settings:   settings,
 	}
 }
 
 // StartRequests 生成初始请求
-func (s *%s) StartRequests() []*request.Request {
-	var requests []*request.Request
-
-	for _, url := range s.StartURLs {
-		req := request.NewRequest("GET", url)
+func (s *%[1]s) StartRequests() []*request.Request {
+	requests := s.BaseSpider.StartRequests()
+	for _, req := range requests {
 		req.SetMeta("callback", "parse")
-		requests = append(requests, req)
 	}
-
 	return requests
 }
 
 // Parse 解析响应
-func (s *%s) Parse(resp *response.Response) []interface{} {
+func (s *%[1]s) Parse(resp *response.Response) []interface{} {
 	// TODO: 实现你的解析逻辑
 	return []interface{}{}
 }
-`, spiderName, spiderName, spiderName, spiderName, spiderName, spiderName, spiderName, spiderName)
+`, spiderName)
 }
 
 // generateReadme 生成 README.md 文件
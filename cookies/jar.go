@@ -0,0 +1,362 @@
+package cookies
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Jar 按RFC 6265管理Cookie的http.CookieJar
+//
+// 与net/http/cookiejar的规则一致：Domain属性不能是公共后缀（如com、co.uk），
+// 没有Domain属性的Cookie只发送给设置它的主机；按Path匹配；Secure Cookie只通过https发送；
+// Max-Age和Expires过期的Cookie被删除。另外可以列出所有Cookie，用于导出。
+type Jar struct {
+	entries map[string]map[string]entry // eTLD+1 -> id -> entry
+	nextSeq uint64
+	mutex   sync.Mutex
+}
+
+// entry 保存的Cookie
+type entry struct {
+	Name       string
+	Value      string
+	Domain     string
+	Path       string
+	Secure     bool
+	HttpOnly   bool
+	Persistent bool
+	HostOnly   bool
+	Expires    time.Time
+	seq        uint64 // 创建顺序，路径长度相同时先创建的在前
+}
+
+// id 同一域名、路径、名称的Cookie相互覆盖
+func (e *entry) id() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+// expired Cookie是否已过期
+func (e *entry) expired(now time.Time) bool {
+	return e.Persistent && !e.Expires.After(now)
+}
+
+// NewJar 创建Cookie Jar
+func NewJar() *Jar {
+	return &Jar{entries: make(map[string]map[string]entry)}
+}
+
+// SetCookies 保存响应中设置的Cookie，实现http.CookieJar
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+	defPath := defaultPath(u.Path)
+	now := time.Now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for _, cookie := range cookies {
+		e, remove, ok := newEntry(cookie, now, defPath, host)
+		if !ok {
+			continue
+		}
+		j.store(e, remove)
+	}
+}
+
+// Cookies 返回应随请求发送的Cookie（只有名称和值），实现http.CookieJar
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	https := u.Scheme == "https"
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	submap := j.entries[jarKey(host)]
+	var selected []entry
+	for id, e := range submap {
+		if e.expired(now) {
+			delete(submap, id)
+			continue
+		}
+		if !e.shouldSend(https, host, path) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+
+	// 路径更长的在前，相同时先创建的在前
+	sort.Slice(selected, func(i, k int) bool {
+		if len(selected[i].Path) != len(selected[k].Path) {
+			return len(selected[i].Path) > len(selected[k].Path)
+		}
+		return selected[i].seq < selected[k].seq
+	})
+
+	cookies := make([]*http.Cookie, len(selected))
+	for i, e := range selected {
+		cookies[i] = &http.Cookie{Name: e.Name, Value: e.Value}
+	}
+	return cookies
+}
+
+// All 返回所有未过期的Cookie（含Domain、Path、Expires等属性），按域名、路径、名称排序
+// 非HostOnly的Cookie的Domain以"."开头
+func (j *Jar) All() []*http.Cookie {
+	now := time.Now()
+
+	j.mutex.Lock()
+	var all []entry
+	for _, submap := range j.entries {
+		for id, e := range submap {
+			if e.expired(now) {
+				delete(submap, id)
+				continue
+			}
+			all = append(all, e)
+		}
+	}
+	j.mutex.Unlock()
+
+	sort.Slice(all, func(i, k int) bool {
+		if all[i].Domain != all[k].Domain {
+			return all[i].Domain < all[k].Domain
+		}
+		if all[i].Path != all[k].Path {
+			return all[i].Path < all[k].Path
+		}
+		return all[i].Name < all[k].Name
+	})
+
+	cookies := make([]*http.Cookie, len(all))
+	for i, e := range all {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+		cookies[i] = &http.Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   domain,
+			Path:     e.Path,
+			Secure:   e.Secure,
+			HttpOnly: e.HttpOnly,
+		}
+		if e.Persistent {
+			cookies[i].Expires = e.Expires
+		}
+	}
+	return cookies
+}
+
+// Len 返回Cookie数量（含已过期但尚未清理的）
+func (j *Jar) Len() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	n := 0
+	for _, submap := range j.entries {
+		n += len(submap)
+	}
+	return n
+}
+
+// Clear 删除所有Cookie
+func (j *Jar) Clear() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.entries = make(map[string]map[string]entry)
+}
+
+// store 保存或删除Cookie，调用方需持有锁
+func (j *Jar) store(e entry, remove bool) {
+	key := jarKey(e.Domain)
+	submap := j.entries[key]
+	id := e.id()
+
+	if remove {
+		delete(submap, id)
+		return
+	}
+
+	if old, exists := submap[id]; exists {
+		// 覆盖时保留创建顺序
+		e.seq = old.seq
+	} else {
+		j.nextSeq++
+		e.seq = j.nextSeq
+	}
+	if submap == nil {
+		submap = make(map[string]entry)
+		j.entries[key] = submap
+	}
+	submap[id] = e
+}
+
+// shouldSend Cookie是否应随请求发送
+func (e *entry) shouldSend(https bool, host, path string) bool {
+	return e.domainMatch(host) && pathMatch(e.Path, path) && (https || !e.Secure)
+}
+
+// domainMatch 主机是否匹配Cookie的域名
+func (e *entry) domainMatch(host string) bool {
+	if e.Domain == host {
+		return true
+	}
+	return !e.HostOnly && strings.HasSuffix(host, "."+e.Domain)
+}
+
+// pathMatch 请求路径是否匹配Cookie路径（RFC 6265 5.1.4）
+func pathMatch(cookiePath, requestPath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if strings.HasPrefix(requestPath, cookiePath) {
+		return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+	}
+	return false
+}
+
+// newEntry 按RFC 6265 5.3由Set-Cookie创建条目，remove表示应删除已有的同名Cookie，ok为false表示拒绝该Cookie
+func newEntry(c *http.Cookie, now time.Time, defPath, host string) (e entry, remove, ok bool) {
+	if c.Name == "" {
+		return e, false, false
+	}
+
+	e.Name = c.Name
+	e.Value = c.Value
+	e.Secure = c.Secure
+	e.HttpOnly = c.HttpOnly
+
+	if c.Path == "" || c.Path[0] != '/' {
+		e.Path = defPath
+	} else {
+		e.Path = c.Path
+	}
+
+	var err error
+	e.Domain, e.HostOnly, err = domainAndType(host, c.Domain)
+	if err != nil {
+		return e, false, false
+	}
+
+	// Max-Age优先于Expires
+	if c.MaxAge < 0 {
+		return e, true, true
+	} else if c.MaxAge > 0 {
+		e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		e.Persistent = true
+	} else if !c.Expires.IsZero() {
+		if !c.Expires.After(now) {
+			return e, true, true
+		}
+		e.Expires = c.Expires
+		e.Persistent = true
+	}
+	return e, false, true
+}
+
+// illegalDomainError Cookie的Domain属性与主机不匹配或是公共后缀
+type illegalDomainError string
+
+func (e illegalDomainError) Error() string {
+	return "cookies: illegal cookie domain attribute " + string(e)
+}
+
+// domainAndType 确定Cookie的域名以及是否只发送给设置它的主机
+func domainAndType(host, domain string) (string, bool, error) {
+	if domain == "" {
+		return host, true, nil
+	}
+
+	if isIP(host) {
+		// IP地址只能设置与自身相同的Domain
+		if host != domain {
+			return "", false, illegalDomainError(domain)
+		}
+		return host, true, nil
+	}
+
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" || strings.HasSuffix(domain, ".") {
+		return "", false, illegalDomainError(domain)
+	}
+
+	// 不能为公共后缀设置Cookie，主机本身是公共后缀时视为HostOnly
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		if host == domain {
+			return host, true, nil
+		}
+		return "", false, illegalDomainError(domain)
+	}
+
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", false, illegalDomainError(domain)
+	}
+	return domain, false, nil
+}
+
+// jarKey Cookie按eTLD+1分组存放
+func jarKey(host string) string {
+	if isIP(host) {
+		return host
+	}
+	key, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return key
+}
+
+// canonicalHost 小写并去掉端口
+func canonicalHost(host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", illegalDomainError(host)
+	}
+	return strings.Trim(host, "[]"), nil
+}
+
+// defaultPath RFC 6265 5.1.4 默认路径：去掉最后一个"/"之后的部分
+func defaultPath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// isIP 主机是否为IP地址
+func isIP(host string) bool {
+	return net.ParseIP(host) != nil
+}
@@ -0,0 +1,67 @@
+package cookies
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultJar 请求Meta中没有cookiejar时使用的Jar名称
+const DefaultJar = ""
+
+// Jars 按名称区分的多个Cookie Jar，同一爬虫可以同时维持多个登录会话
+type Jars struct {
+	jars  map[string]*Jar
+	mutex sync.Mutex
+}
+
+// NewJars 创建Jar集合
+func NewJars() *Jars {
+	return &Jars{jars: make(map[string]*Jar)}
+}
+
+// Jar 返回指定名称的Jar，不存在时创建
+//
+// key通常是请求Meta中cookiejar的值，可以是字符串或数字；
+// 数字按十进制字符串处理，因此1和从JOBDIR恢复后的1.0对应同一个Jar。nil对应默认Jar。
+func (j *Jars) Jar(key interface{}) *Jar {
+	name := jarName(key)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	jar, exists := j.jars[name]
+	if !exists {
+		jar = NewJar()
+		j.jars[name] = jar
+	}
+	return jar
+}
+
+// Names 返回已创建的Jar名称
+func (j *Jars) Names() []string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	names := make([]string, 0, len(j.jars))
+	for name := range j.jars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jarName 将Meta中的值转换为Jar名称
+func jarName(key interface{}) string {
+	switch v := key.(type) {
+	case nil:
+		return DefaultJar
+	case string:
+		return v
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprint(int64(v))
+		}
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,143 @@
+package cookies
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// netscapeHeader Netscape格式Cookie文件的首行（curl、wget、浏览器导出插件通用）
+const netscapeHeader = "# Netscape HTTP Cookie File"
+
+// httpOnlyPrefix curl用于标记HttpOnly Cookie的行前缀
+const httpOnlyPrefix = "#HttpOnly_"
+
+// WriteNetscape 以Netscape格式写出Jar中的所有Cookie，会话Cookie的过期时间为0
+func WriteNetscape(w io.Writer, jar *Jar) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, netscapeHeader)
+	fmt.Fprintln(bw)
+
+	for _, c := range jar.All() {
+		domain := c.Domain
+		if c.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(strings.HasPrefix(c.Domain, ".")), c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+	}
+	return bw.Flush()
+}
+
+// ReadNetscape 读取Netscape格式的Cookie并加入Jar，返回读取的Cookie数量
+// 过期时间为0的作为会话Cookie，已过期的忽略
+func ReadNetscape(r io.Reader, jar *Jar) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	count := 0
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			httpOnly = true
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return count, fmt.Errorf("cookies: line %d: expected 7 tab-separated fields, got %d", lineNo, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return count, fmt.Errorf("cookies: line %d: invalid expires %q", lineNo, fields[4])
+		}
+
+		domain := fields[0]
+		includeSubdomains := strings.EqualFold(fields[1], "TRUE")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if includeSubdomains {
+			cookie.Domain = domain
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+			if !cookie.Expires.After(time.Now()) {
+				continue
+			}
+		}
+
+		// 以Cookie所属的主机作为来源设置，Domain规则与响应设置的Cookie相同
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		host := strings.TrimPrefix(domain, ".")
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// LoadFile 从Netscape格式的文件读取Cookie
+func LoadFile(path string, jar *Jar) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return ReadNetscape(file, jar)
+}
+
+// SaveFile 将Cookie保存为Netscape格式的文件，先写临时文件再重命名
+func SaveFile(path string, jar *Jar) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := WriteNetscape(file, jar); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
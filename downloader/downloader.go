@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"scrago/cookies"
 	"scrago/request"
 	"scrago/response"
 	"io"
@@ -35,6 +36,7 @@ type AsyncResult struct {
 type HTTPDownloader struct {
 	client   *http.Client
 	userAgent string
+	cookieJars *cookies.Jars
 }
 
 // NewHTTPDownloader 创建HTTP下载器
@@ -75,11 +77,36 @@ func (d *HTTPDownloader) Download(req *request.Request) (*response.Response, err
 		return nil, fmt.Errorf("build request failed: %w", err)
 	}
 	
+	// 记录重定向经过的URL
+	var redirectURLs []string
+	
 	// 创建独立的客户端副本以避免并发问题
 	client := &http.Client{
 		Transport: d.client.Transport,
 		Timeout:   10 * time.Second, // 减少超时时间以快速发现问题
-		CheckRedirect: d.client.CheckRedirect,
+		CheckRedirect: func(httpReq *http.Request, via []*http.Request) error {
+			if req.DontRedirect {
+				return http.ErrUseLastResponse
+			}
+			if err := d.client.CheckRedirect(httpReq, via); err != nil {
+				return err
+			}
+			redirectURLs = append(redirectURLs, via[len(via)-1].URL.String())
+			return nil
+		},
+	}
+	
+	// 设置Cookie Jar，重定向过程中设置的Cookie也会保存
+	if jar := d.cookieJar(req); jar != nil {
+		client.Jar = jar
+		if len(req.Cookies) > 0 {
+			// 请求自带的Cookie并入Jar，之后的请求继续使用
+			jar.SetCookies(httpReq.URL, req.Cookies)
+		}
+	} else {
+		for _, cookie := range req.Cookies {
+			httpReq.AddCookie(cookie)
+		}
 	}
 	
 	// 设置代理
@@ -151,6 +178,15 @@ func (d *HTTPDownloader) Download(req *request.Request) (*response.Response, err
 		req,
 	)
 	
+	// 记录下载信息到Meta（与请求共享）
+	resp.SetMeta(request.MetaDownloadLatency, time.Since(start))
+	if len(redirectURLs) > 0 {
+		resp.SetMeta(request.MetaRedirectURLs, redirectURLs)
+	}
+	if req.Proxy != "" {
+		resp.SetMeta(request.MetaProxy, req.Proxy)
+	}
+	
 	return resp, nil
 }
 
@@ -178,11 +214,6 @@ func (d *HTTPDownloader) buildHTTPRequest(req *request.Request) (*http.Request,
 		httpReq.Header.Set("User-Agent", d.userAgent)
 	}
 	
-	// 设置Cookies
-	for _, cookie := range req.Cookies {
-		httpReq.AddCookie(cookie)
-	}
-	
 	return httpReq, nil
 }
 
@@ -216,10 +247,28 @@ func (d *HTTPDownloader) SetProxy(proxyURL string) error {
 	return nil
 }
 
-// EnableCookieJar 启用Cookie管理
+// EnableCookieJar 启用Cookie管理：没有设置CookieJar的请求按Meta["cookiejar"]使用下载器自带的Cookie Jar
 func (d *HTTPDownloader) EnableCookieJar() {
-	// 这里可以实现Cookie Jar功能
-	// d.client.Jar = cookiejar.New(nil)
+	d.cookieJars = cookies.NewJars()
+}
+
+// CookieJars 返回下载器自带的Cookie Jar，未启用时返回nil
+func (d *HTTPDownloader) CookieJars() *cookies.Jars {
+	return d.cookieJars
+}
+
+// cookieJar 请求使用的Cookie Jar：请求的CookieJar优先，其次是下载器自带的
+func (d *HTTPDownloader) cookieJar(req *request.Request) http.CookieJar {
+	if req.CookieJar != nil {
+		return req.CookieJar
+	}
+	if d.cookieJars == nil {
+		return nil
+	}
+	if dontMerge, _ := req.Meta[request.MetaDontMergeCookies].(bool); dontMerge {
+		return nil
+	}
+	return d.cookieJars.Jar(req.Meta[request.MetaCookieJar])
 }
 
 // SetTLSConfig 设置TLS配置
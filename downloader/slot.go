@@ -0,0 +1,307 @@
+package downloader
+
+import (
+	"math/rand"
+	"net"
+	"net/url"
+	"scrago/request"
+	"sync"
+	"time"
+)
+
+// downloadSlot 下载槽：同一域名（或IP）的请求共享并发上限和下载延迟
+type downloadSlot struct {
+	concurrency int
+	delay       time.Duration
+	minDelay    time.Duration // 下载延迟下限，如robots.txt的Crawl-delay
+	randomize   bool
+	active      int
+	nextStart   time.Time
+	held        []*request.Request
+}
+
+// SlotManager 下载槽管理器
+//
+// 引擎在下载前为请求获取下载槽，槽已满或未到下载间隔时请求被暂存，
+// 其他域名的请求不受影响；下载完成后释放下载槽，暂存的请求按顺序放行。
+type SlotManager struct {
+	perDomain int
+	perIP     int
+	delay     time.Duration
+	randomize bool
+	slots     map[string]*downloadSlot
+	heldKeys  []string // 有暂存请求的下载槽，按暂存顺序轮流放行
+	heldCount int
+	ipCache   map[string]string
+	mutex     sync.Mutex
+}
+
+// NewSlotManager 创建下载槽管理器
+// perIP大于0时按解析出的IP划分下载槽并使用perIP作为并发上限，否则按域名划分
+func NewSlotManager(perDomain, perIP int, delay time.Duration, randomize bool) *SlotManager {
+	if perDomain <= 0 {
+		perDomain = 8
+	}
+
+	return &SlotManager{
+		perDomain: perDomain,
+		perIP:     perIP,
+		delay:     delay,
+		randomize: randomize,
+		slots:     make(map[string]*downloadSlot),
+		ipCache:   make(map[string]string),
+	}
+}
+
+// SlotKey 获取请求所属的下载槽，可通过Meta["download_slot"]指定
+func (m *SlotManager) SlotKey(req *request.Request) string {
+	if key, ok := req.Meta[request.MetaDownloadSlot].(string); ok && key != "" {
+		return key
+	}
+
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil {
+		return req.URL
+	}
+	host := parsedURL.Hostname()
+
+	if m.perIP > 0 {
+		return m.resolve(host)
+	}
+	return host
+}
+
+// resolve 解析域名对应的IP，结果会被缓存，解析失败时使用域名
+func (m *SlotManager) resolve(host string) string {
+	m.mutex.Lock()
+	ip, exists := m.ipCache[host]
+	m.mutex.Unlock()
+	if exists {
+		return ip
+	}
+
+	ip = host
+	if addrs, err := net.LookupIP(host); err == nil && len(addrs) > 0 {
+		ip = addrs[0].String()
+	}
+
+	m.mutex.Lock()
+	m.ipCache[host] = ip
+	m.mutex.Unlock()
+	return ip
+}
+
+// slot 获取（或创建）下载槽，调用方需持有锁
+func (m *SlotManager) slot(key string) *downloadSlot {
+	if s, exists := m.slots[key]; exists {
+		return s
+	}
+
+	concurrency := m.perDomain
+	if m.perIP > 0 {
+		concurrency = m.perIP
+	}
+
+	s := &downloadSlot{
+		concurrency: concurrency,
+		delay:       m.delay,
+		randomize:   m.randomize,
+	}
+	m.slots[key] = s
+	return s
+}
+
+// Configure 为指定下载槽单独设置并发上限和下载延迟
+func (m *SlotManager) Configure(key string, concurrency int, delay time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := m.slot(key)
+	if concurrency > 0 {
+		s.concurrency = concurrency
+	}
+	s.setDelay(delay)
+}
+
+// SetDefaultDelay 设置新建下载槽的下载延迟
+func (m *SlotManager) SetDefaultDelay(delay time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.delay = delay
+}
+
+// SetDelay 设置下载槽的下载延迟
+func (m *SlotManager) SetDelay(key string, delay time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.slot(key).setDelay(delay)
+}
+
+// SetMinDelay 设置下载槽的延迟下限，之后设置的延迟不会低于该值
+func (m *SlotManager) SetMinDelay(key string, minDelay time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := m.slot(key)
+	s.minDelay = minDelay
+	s.setDelay(s.delay)
+}
+
+// setDelay 设置下载延迟，不低于延迟下限，调用方需持有锁
+func (s *downloadSlot) setDelay(delay time.Duration) {
+	if delay < s.minDelay {
+		delay = s.minDelay
+	}
+	s.delay = delay
+}
+
+// Delay 获取下载槽的下载延迟
+func (m *SlotManager) Delay(key string) time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.slot(key).delay
+}
+
+// SetConcurrency 设置下载槽的并发上限
+func (m *SlotManager) SetConcurrency(key string, concurrency int) {
+	if concurrency <= 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.slot(key).concurrency = concurrency
+}
+
+// Concurrency 获取下载槽的并发上限
+func (m *SlotManager) Concurrency(key string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.slot(key).concurrency
+}
+
+// canStart 下载槽是否可以开始新的下载，调用方需持有锁
+func (s *downloadSlot) canStart(now time.Time) bool {
+	return s.active < s.concurrency && !now.Before(s.nextStart)
+}
+
+// start 占用下载槽并计算下一次允许开始下载的时间，调用方需持有锁
+func (s *downloadSlot) start(now time.Time) {
+	s.active++
+
+	delay := s.delay
+	if s.randomize && delay > 0 {
+		// 随机化延迟时间（0.5 * delay 到 1.5 * delay）
+		delay = time.Duration(float64(delay) * (0.5 + rand.Float64()))
+	}
+	s.nextStart = now.Add(delay)
+}
+
+// TryAcquire 尝试为请求占用下载槽，成功时返回true，之后必须调用Release
+// 同一下载槽已有暂存请求时不会插队
+func (m *SlotManager) TryAcquire(key string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := m.slot(key)
+	now := time.Now()
+	if len(s.held) > 0 || !s.canStart(now) {
+		return false
+	}
+	s.start(now)
+	return true
+}
+
+// Release 释放下载槽
+func (m *SlotManager) Release(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if s, exists := m.slots[key]; exists && s.active > 0 {
+		s.active--
+	}
+}
+
+// ReleaseUnused 释放没有实际下载（如命中缓存）的下载槽，下一个请求无需等待下载间隔
+func (m *SlotManager) ReleaseUnused(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if s, exists := m.slots[key]; exists && s.active > 0 {
+		s.active--
+		s.nextStart = time.Now()
+	}
+}
+
+// Hold 暂存暂时无法下载的请求
+func (m *SlotManager) Hold(key string, req *request.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := m.slot(key)
+	if len(s.held) == 0 {
+		m.heldKeys = append(m.heldKeys, key)
+	}
+	s.held = append(s.held, req)
+	m.heldCount++
+}
+
+// NextReady 取出一个下载槽已空闲的暂存请求并占用其下载槽，没有时返回nil
+func (m *SlotManager) NextReady() (*request.Request, string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	for i, key := range m.heldKeys {
+		s := m.slots[key]
+		if !s.canStart(now) {
+			continue
+		}
+
+		req := s.held[0]
+		s.held[0] = nil
+		s.held = s.held[1:]
+		m.heldCount--
+		s.start(now)
+
+		// 移到队尾，让其他下载槽的请求轮流放行
+		m.heldKeys = append(m.heldKeys[:i], m.heldKeys[i+1:]...)
+		if len(s.held) > 0 {
+			m.heldKeys = append(m.heldKeys, key)
+		}
+		return req, key
+	}
+	return nil, ""
+}
+
+// Held 暂存的请求数
+func (m *SlotManager) Held() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.heldCount
+}
+
+// Drain 取出全部暂存的请求
+func (m *SlotManager) Drain() []*request.Request {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var reqs []*request.Request
+	for _, key := range m.heldKeys {
+		s := m.slots[key]
+		reqs = append(reqs, s.held...)
+		s.held = nil
+	}
+	m.heldKeys = nil
+	m.heldCount = 0
+	return reqs
+}
+
+// Stats 下载槽统计
+func (m *SlotManager) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return map[string]int64{
+		"downloader/slots": int64(len(m.slots)),
+	}
+}
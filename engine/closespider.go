@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// 爬虫关闭原因
+const (
+	CloseReasonFinished   = "finished"               // 所有请求处理完成
+	CloseReasonShutdown   = "shutdown"               // 外部取消（如Ctrl+C）
+	CloseReasonItemCount  = "closespider_itemcount"  // 达到CLOSESPIDER_ITEMCOUNT
+	CloseReasonPageCount  = "closespider_pagecount"  // 达到CLOSESPIDER_PAGECOUNT
+	CloseReasonErrorCount = "closespider_errorcount" // 达到CLOSESPIDER_ERRORCOUNT
+	CloseReasonTimeout    = "closespider_timeout"    // 达到CLOSESPIDER_TIMEOUT
+)
+
+// CloseSpiderConditions 自动关闭爬虫的条件，为0的条件不启用
+// 计数只统计本次运行，不包含从JOBDIR恢复的统计信息
+type CloseSpiderConditions struct {
+	ItemCount  int64         // 抓取的数据项数
+	PageCount  int64         // 下载成功的响应数
+	ErrorCount int64         // 爬虫回调的异常数
+	Timeout    time.Duration // 运行时长
+}
+
+// SpiderClosedError 爬虫因CloseSpider提前关闭时由Run返回，已完成优雅关闭
+type SpiderClosedError struct {
+	Reason string
+}
+
+func (e *SpiderClosedError) Error() string {
+	return fmt.Sprintf("spider closed: %s", e.Reason)
+}
+
+// SetCloseSpider 设置自动关闭爬虫的条件
+func (e *Engine) SetCloseSpider(conditions CloseSpiderConditions) {
+	e.closeConditions = conditions
+}
+
+// CloseSpider 请求优雅关闭爬虫：停止调度新请求，等待进行中的请求完成。
+// 多次调用时以第一次的原因为准
+func (e *Engine) CloseSpider(reason string) {
+	e.closeMu.Lock()
+	defer e.closeMu.Unlock()
+
+	if e.closeReason != "" {
+		return
+	}
+	e.closeReason = reason
+	fmt.Printf("🛑 关闭爬虫: %s\n", reason)
+	if e.stopRun != nil {
+		e.stopRun()
+	}
+}
+
+// CloseReason 返回爬虫的关闭原因，运行中未请求关闭时为空
+func (e *Engine) CloseReason() string {
+	e.closeMu.Lock()
+	defer e.closeMu.Unlock()
+	return e.closeReason
+}
+
+// checkCloseSpider 统计本次运行的计数，达到条件时关闭爬虫
+func (e *Engine) checkCloseSpider(key string, value int64) {
+	var count, limit int64
+	var reason string
+	switch key {
+	case "item_scraped_count":
+		count, limit, reason = e.closeCounts.items.Add(value), e.closeConditions.ItemCount, CloseReasonItemCount
+	case "response_received_count":
+		count, limit, reason = e.closeCounts.pages.Add(value), e.closeConditions.PageCount, CloseReasonPageCount
+	case "spider_exceptions/count":
+		count, limit, reason = e.closeCounts.errors.Add(value), e.closeConditions.ErrorCount, CloseReasonErrorCount
+	default:
+		return
+	}
+
+	if limit > 0 && count >= limit {
+		e.CloseSpider(reason)
+	}
+}
@@ -2,14 +2,24 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"scrago/downloader"
+	"scrago/extension"
 	"scrago/middleware"
 	"scrago/pipeline"
+	"scrago/registry"
 	"scrago/request"
+	"scrago/response"
 	"scrago/scheduler"
+	"scrago/settings"
+	"scrago/signals"
 	"scrago/spider"
+	"scrago/stats"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,39 +29,71 @@ type Engine struct {
 	downloader  downloader.Downloader
 	pipelines   []pipeline.Pipeline
 	middlewares []middleware.Middleware
+	spiderMiddlewares []middleware.SpiderMiddleware
+	
+	// 站外过滤，在所有爬虫中间件之后执行，爬虫实现AllowedDomainsProvider时生效
+	offsite     *middleware.OffsiteMiddleware
 	
 	// 并发控制
 	concurrency int
 	workers     chan struct{}
 	wg          sync.WaitGroup
 	
+	// 🚀 按域名/IP划分的下载槽，槽满的请求暂存，其余域名的请求照常下载
+	slots       *downloader.SlotManager
+	maxHeld     int
+	
+	// 扩展，按添加顺序打开，按相反顺序关闭
+	extensions  []extension.Extension
+	
 	// 🚀 结果处理协程池 - 专门处理yield返回的请求
 	resultPool     chan interface{}
 	resultWorkers  int
 	resultWg       sync.WaitGroup
 	
-	// 🚀 当前运行的spider实例（用于异步批量处理）
-	currentSpider  spider.Spider
-	spiderMutex    sync.RWMutex
+	resultsStop    chan struct{}
+	stopOnce       sync.Once
+	sendWg         sync.WaitGroup
+	
+	// 关闭等待超时后丢弃仍在产生的响应和结果，避免写入已关闭的组件
+	abandoned      atomic.Bool
+	
+	// 🚀 在途请求计数 - 排队、下载、解析中及结果未处理的请求都计入其中
+	inflight       *inflightCounter
+	wakeup         chan struct{}
+	
+	// 运行结束后不再接受新请求（如延迟重试）
+	closed         atomic.Bool
 	
-	// 🚀 异步批量处理等待机制
-	batchWg        sync.WaitGroup
+	// ⏳ 等待退避的重试请求，关闭时放回调度器以便随队列一起持久化
+	delayed        map[*request.Request]*time.Timer
+	delayedMu      sync.Mutex
 	
-	// 统计信息
-	stats       *Stats
+	// 📡 生命周期信号，scheduled用于判断spider_idle处理函数是否添加了请求
+	signals        *signals.Manager
+	scheduled      atomic.Int64
+	
+	// 持久化目录，用于暂停和恢复爬取
+	jobDir         string
+	
+	// 🛑 自动关闭条件，达到时取消本次运行并记录关闭原因
+	closeConditions CloseSpiderConditions
+	closeCounts     struct{ items, pages, errors atomic.Int64 }
+	closeReason     string
+	stopRun         context.CancelFunc
+	closeMu         sync.Mutex
+	
+	// 统计信息，运行结束后合并各组件的统计，可写入JSON文件
+	stats       stats.Collector
+	statsFile   string
 	
 	// 配置
 	settings    *Settings
 }
 
-// Stats 统计信息
-type Stats struct {
-	RequestsTotal    int64
-	RequestsSuccess  int64
-	RequestsFailed   int64
-	ItemsScraped     int64
-	StartTime        time.Time
-	mu               sync.RWMutex
+// statsProvider 提供额外统计信息的组件
+type statsProvider interface {
+	Stats() map[string]int64
 }
 
 // Settings 引擎配置
@@ -64,6 +106,7 @@ type Settings struct {
 	AutoThrottle     bool
 	RetryTimes       int
 	RetryHTTPCodes   []int
+	ShutdownTimeout  time.Duration
 }
 
 // NewEngine 创建新的爬虫引擎
@@ -74,9 +117,10 @@ func NewEngine() *Engine {
 		RandomizeDelay: true,
 		UserAgent:      "Go-Scrapy/1.0",
 		RobotsTxtObey:  true,
-		AutoThrottle:   true,
+		AutoThrottle:   false,
 		RetryTimes:     3,
 		RetryHTTPCodes: []int{500, 502, 503, 504, 408, 429},
+		ShutdownTimeout: 30 * time.Second,
 	}
 	
 	resultWorkers := settings.Concurrency / 2
@@ -85,20 +129,31 @@ func NewEngine() *Engine {
 	}
 	
 	return &Engine{
-		scheduler:   scheduler.NewChannelScheduler(settings.Concurrency * 4), // 使用高性能调度器
+		// 默认使用高性能调度器，并在入队时按请求指纹去重；ApplySettings按SCHEDULER替换
+		scheduler:   scheduler.NewDupeFilterScheduler(
+			scheduler.NewChannelScheduler(settings.Concurrency * 4),
+			scheduler.NewRFPDupeFilter(scheduler.NewMemoryStore()),
+		),
 		downloader:  downloader.NewHTTPDownloader(),
 		pipelines:   make([]pipeline.Pipeline, 0),
 		middlewares: make([]middleware.Middleware, 0),
+		offsite:     middleware.NewOffsiteMiddleware(),
+		extensions:  []extension.Extension{extension.NewCoreStats()},
 		concurrency: settings.Concurrency,
 		workers:     make(chan struct{}, settings.Concurrency),
+		slots:       downloader.NewSlotManager(8, 0, 0, false),
+		maxHeld:     settings.Concurrency * 8,
 		
 		// 🚀 初始化结果处理协程池
 		resultPool:    make(chan interface{}, settings.Concurrency * 8),
 		resultWorkers: resultWorkers,
+		resultsStop:   make(chan struct{}),
 		
-		stats: &Stats{
-			StartTime: time.Now(),
-		},
+		inflight: newInflightCounter(),
+		wakeup:   make(chan struct{}, settings.Concurrency),
+		signals:  signals.NewManager(),
+		
+		stats:    stats.NewMemoryCollector(),
 		settings: settings,
 	}
 }
@@ -113,16 +168,179 @@ func (e *Engine) AddMiddleware(m middleware.Middleware) {
 	e.middlewares = append(e.middlewares, m)
 }
 
+// AddSpiderMiddleware 添加爬虫中间件
+func (e *Engine) AddSpiderMiddleware(m middleware.SpiderMiddleware) {
+	e.spiderMiddlewares = append(e.spiderMiddlewares, m)
+}
+
+// AddExtension 添加扩展
+func (e *Engine) AddExtension(ext extension.Extension) {
+	e.extensions = append(e.extensions, ext)
+}
+
+// SetExtensions 替换全部扩展（默认只有CoreStats）
+func (e *Engine) SetExtensions(extensions []extension.Extension) {
+	e.extensions = extensions
+}
+
+// ApplySettings 通过registry按设置中的顺序表（DOWNLOADER_MIDDLEWARES、SPIDER_MIDDLEWARES、
+// ITEM_PIPELINES、EXTENSIONS）创建组件，替换引擎现有的中间件、管道和扩展；
+// 并按SCHEDULER、去重设置和JOBDIR（按SCHEDULER_DISK_QUEUE出队）替换调度器。
+// 需在SetDownloadSlots之后调用，robots.txt中间件的Crawl-delay作用于当前的下载槽
+func (e *Engine) ApplySettings(s *settings.Settings) error {
+	middlewares, err := registry.DownloaderMiddlewares(s)
+	if err != nil {
+		return err
+	}
+	spiderMiddlewares, err := registry.SpiderMiddlewares(s)
+	if err != nil {
+		return err
+	}
+	pipelines, err := registry.Pipelines(s)
+	if err != nil {
+		return err
+	}
+	extensions, err := registry.Extensions(s)
+	if err != nil {
+		return err
+	}
+	if err := e.applySchedulerSettings(s); err != nil {
+		return err
+	}
+	
+	for _, mw := range middlewares {
+		if robots, ok := mw.(*middleware.RobotsTxtMiddleware); ok {
+			robots.SetDownloadSlots(e.slots)
+		}
+	}
+	
+	e.middlewares = middlewares
+	e.spiderMiddlewares = spiderMiddlewares
+	e.pipelines = pipelines
+	e.extensions = extensions
+	return nil
+}
+
+// applySchedulerSettings 按设置创建调度器，指定JOBDIR时使用可恢复的磁盘队列
+func (e *Engine) applySchedulerSettings(s *settings.Settings) error {
+	if s.JobDir != "" {
+		if err := e.SetJobDir(s.JobDir, scheduler.QueueOrder(strings.ToLower(s.SchedulerDiskQueue))); err != nil {
+			return fmt.Errorf("failed to open jobdir: %w", err)
+		}
+		return nil
+	}
+	
+	name := s.Scheduler
+	if s.DepthPriority != 0 && (name == "" || strings.EqualFold(name, "channel")) {
+		// 按深度调整的优先级需要优先级调度器才能生效
+		name = "priority"
+	}
+	bufferSize := s.ConcurrentRequests * 4
+	if bufferSize <= 0 {
+		bufferSize = e.concurrency * 4
+	}
+	sched, err := scheduler.NewScheduler(name, bufferSize)
+	if err != nil {
+		return err
+	}
+	
+	if s.DupeFilterEnabled {
+		store, err := scheduler.NewFingerprintStore(s.DupeFilterBackend, s.DupeFilterPath)
+		if err != nil {
+			return fmt.Errorf("failed to create dupefilter: %w", err)
+		}
+		sched = scheduler.NewDupeFilterScheduler(sched, scheduler.NewRFPDupeFilter(store))
+	}
+	e.scheduler = sched
+	return nil
+}
+
+// SetScheduler 设置调度器
+func (e *Engine) SetScheduler(s scheduler.Scheduler) {
+	e.scheduler = s
+}
+
+// SetDownloadSlots 设置下载槽管理器
+func (e *Engine) SetDownloadSlots(m *downloader.SlotManager) {
+	e.slots = m
+}
+
+// DownloadSlots 返回下载槽管理器
+func (e *Engine) DownloadSlots() *downloader.SlotManager {
+	return e.slots
+}
+
+// SetShutdownTimeout 设置优雅关闭时等待进行中请求的最长时间
+func (e *Engine) SetShutdownTimeout(timeout time.Duration) {
+	e.settings.ShutdownTimeout = timeout
+}
+
+// Signals 返回信号管理器，用于订阅引擎的生命周期信号
+func (e *Engine) Signals() *signals.Manager {
+	return e.signals
+}
+
+// Crawl 调度新请求，可在信号处理函数（如spider_idle）中调用使爬虫继续运行，
+// 请求不经过爬虫中间件，引擎结束后调用无效
+func (e *Engine) Crawl(req *request.Request) {
+	e.schedule(req)
+}
+
+// SetStatsCollector 设置统计收集器，需在Run之前调用
+func (e *Engine) SetStatsCollector(c stats.Collector) {
+	e.stats = c
+}
+
+// Stats 返回统计收集器，Run返回后包含本次运行的全部统计
+func (e *Engine) Stats() stats.Collector {
+	return e.stats
+}
+
+// SetStatsFile 设置运行结束后写入统计信息的JSON文件
+func (e *Engine) SetStatsFile(path string) {
+	e.statsFile = path
+}
+
+// Scheduler 返回调度器
+func (e *Engine) Scheduler() scheduler.Scheduler {
+	return e.scheduler
+}
+
 // SetConcurrency 设置并发数
 func (e *Engine) SetConcurrency(concurrency int) {
 	e.concurrency = concurrency
 	e.settings.Concurrency = concurrency
 	e.workers = make(chan struct{}, concurrency)
+	e.maxHeld = concurrency * 8
+	e.wakeup = make(chan struct{}, concurrency)
 }
 
 // Run 运行爬虫
-func (e *Engine) Run(s spider.Spider) error {
+// ctx被取消或达到CloseSpider条件时优雅关闭：停止调度新请求，等待进行中的请求完成（最长ShutdownTimeout），
+// 然后关闭管道并打印统计信息。因CloseSpider关闭时返回*SpiderClosedError
+func (e *Engine) Run(ctx context.Context, s spider.Spider) error {
 	fmt.Printf("Starting spider: %s\n", s.Name())
+	e.signals.Send(signals.Event{Signal: signals.EngineStarted})
+	
+	// 爬虫、中间件和管道通过CollectorAware获取统计收集器，通过Subscriber订阅信号
+	components := []interface{}{s}
+	for _, mw := range e.middlewares {
+		components = append(components, mw)
+	}
+	for _, mw := range e.spiderMiddlewares {
+		components = append(components, mw)
+	}
+	for _, p := range e.pipelines {
+		components = append(components, p)
+	}
+	for _, c := range components {
+		if aware, ok := c.(stats.CollectorAware); ok {
+			aware.SetStatsCollector(e.stats)
+		}
+		if sub, ok := c.(signals.Subscriber); ok {
+			sub.ConnectSignals(e.signals)
+		}
+	}
 	
 	// 🚀 打开所有管道
 	for _, p := range e.pipelines {
@@ -138,52 +356,211 @@ func (e *Engine) Run(s spider.Spider) error {
 				fmt.Printf("Warning: failed to close pipeline: %v\n", err)
 			}
 		}
+		e.signals.Send(signals.Event{Signal: signals.EngineStopped})
 	}()
 	
-	// 🚀 设置当前spider实例（用于异步批量处理）
-	e.spiderMutex.Lock()
-	e.currentSpider = s
-	e.spiderMutex.Unlock()
+	// 🚀 恢复上次运行的统计信息（JOBDIR）
+	if e.jobDir != "" {
+		if err := e.loadJobStats(); err != nil {
+			fmt.Printf("Warning: failed to load job stats: %v\n", err)
+		}
+	}
+	
+	// 调度器丢弃已入队的请求（如磁盘队列中损坏的记录）时扣除在途数，避免引擎永远等待
+	if notifier, ok := e.scheduler.(scheduler.DropNotifier); ok {
+		notifier.SetDropHandler(func(n int) { e.inflight.Add(int64(-n)) })
+	}
+	
+	// 调度器中已有的请求（如从JOBDIR恢复的队列）计入在途数
+	if pending := e.scheduler.Size(); pending > 0 {
+		fmt.Printf("♻️  恢复了 %d 个未完成的请求\n", pending)
+		e.inflight.Add(int64(pending))
+	}
+	
+	// 🔌 打开扩展，扩展在此订阅信号；正常结束时在spider_closed之后关闭，提前返回时由defer关闭
+	opened := 0
+	defer func() {
+		for i := opened - 1; i >= 0; i-- {
+			if err := e.extensions[i].Close(); err != nil {
+				fmt.Printf("Warning: failed to close extension: %v\n", err)
+			}
+		}
+	}()
+	for _, ext := range e.extensions {
+		if err := ext.Open(e); err != nil {
+			return fmt.Errorf("failed to open extension %T: %w", ext, err)
+		}
+		opened++
+	}
+	
+	e.signals.Send(signals.Event{Signal: signals.SpiderOpened, Spider: s})
+	
+	// 🛑 CloseSpider取消runCtx，与外部取消走同一条优雅关闭流程
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+	e.closeMu.Lock()
+	e.stopRun = stopRun
+	e.closeMu.Unlock()
+	if timeout := e.closeConditions.Timeout; timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { e.CloseSpider(CloseReasonTimeout) })
+		defer timer.Stop()
+	}
 	
 	// 初始化爬虫
 	startRequests := s.StartRequests()
+	for _, mw := range e.spiderMiddlewares {
+		startRequests = mw.ProcessStartRequests(startRequests, s)
+	}
 	for _, req := range startRequests {
-		e.scheduler.Enqueue(req)
+		e.schedule(req)
 	}
 	
-	// 启动上下文
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// 工作协程上下文，与外部ctx分离，以便关闭时让进行中的请求完成
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
 	
 	// 🚀 启动结果处理协程池
-	e.startResultWorkers(ctx)
+	e.startResultWorkers()
 	
 	// 启动主工作协程池
 	for i := 0; i < e.concurrency; i++ {
 		e.wg.Add(1)
-		go e.worker(ctx, s)
+		go e.worker(workerCtx, s)
 	}
 	
-	// 等待所有任务完成
-	e.wg.Wait()
+	// 🚀 等待爬虫空闲：队列、下载、解析和结果处理全部完成，且spider_idle处理函数没有添加请求
+	finishReason := CloseReasonFinished
+	if err := e.waitUntilIdle(runCtx, s); err != nil {
+		finishReason = CloseReasonShutdown
+		if reason := e.CloseReason(); reason != "" {
+			finishReason = reason
+		}
+		e.shutdown(cancelWorkers)
+	} else {
+		cancelWorkers()
+		e.wg.Wait()
+		e.stopResultWorkers()
+	}
+	e.closeDelayed()
+	
+	// 暂存在下载槽中的请求放回调度器，以便随队列一起持久化
+	for _, req := range e.slots.Drain() {
+		req.DontFilter = true
+		e.scheduler.Enqueue(req)
+	}
 	
-	// 🚀 等待所有异步批量处理完成
-	fmt.Printf("⏳ 等待异步批量处理完成...\n")
-	e.batchWg.Wait()
-	fmt.Printf("✅ 所有异步批量处理已完成\n")
+	// 关闭调度器（如持久化的去重指纹文件）
+	if closer, ok := e.scheduler.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			fmt.Printf("Warning: failed to close scheduler: %v\n", err)
+		}
+	}
 	
-	// 关闭结果处理协程池
-	close(e.resultPool)
-	e.resultWg.Wait()
+	// 关闭下载中间件（如HTTP缓存存储）
+	for _, mw := range e.middlewares {
+		if closer, ok := mw.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				fmt.Printf("Warning: failed to close middleware: %v\n", err)
+			}
+		}
+	}
 	
-	// 打印统计信息
+	// 保存统计信息，下次使用相同JOBDIR时继续累计
+	if e.jobDir != "" {
+		if err := e.saveJobStats(); err != nil {
+			fmt.Printf("Warning: failed to save job stats: %v\n", err)
+		}
+		if pending := e.scheduler.Size(); pending > 0 {
+			fmt.Printf("💾 %d 个未完成的请求已保存到 %s，使用相同的JOBDIR即可继续\n", pending, e.jobDir)
+		}
+	}
+	
+	e.stats.Set("finish_reason", finishReason)
+	e.signals.Send(signals.Event{Signal: signals.SpiderClosed, Spider: s, Reason: finishReason})
+	
+	// 关闭扩展后合并组件统计并打印，指定了统计文件时写入JSON
+	for ; opened > 0; opened-- {
+		if err := e.extensions[opened-1].Close(); err != nil {
+			fmt.Printf("Warning: failed to close extension: %v\n", err)
+		}
+	}
+	e.collectComponentStats()
 	e.printStats()
+	if e.statsFile != "" {
+		if err := stats.WriteJSON(e.stats, e.statsFile); err != nil {
+			fmt.Printf("Warning: failed to write stats file: %v\n", err)
+		} else {
+			fmt.Printf("📊 统计信息已写入 %s\n", e.statsFile)
+		}
+	}
 	
+	if finishReason != CloseReasonFinished && finishReason != CloseReasonShutdown {
+		return &SpiderClosedError{Reason: finishReason}
+	}
 	return nil
 }
 
+// idleInterval spider_idle处理函数要求不关闭爬虫时，再次发送spider_idle的间隔
+const idleInterval = 5 * time.Second
+
+// waitUntilIdle 等待在途请求归零后发送spider_idle。处理函数添加了请求时继续等待，
+// 返回ErrDontCloseSpider时每隔idleInterval再次发送；ctx取消时返回ctx的错误
+func (e *Engine) waitUntilIdle(ctx context.Context, s spider.Spider) error {
+	for {
+		if err := e.inflight.Wait(ctx); err != nil {
+			return err
+		}
+		
+		scheduled := e.scheduled.Load()
+		err := e.signals.Send(signals.Event{Signal: signals.SpiderIdle, Spider: s})
+		if e.scheduled.Load() != scheduled || e.inflight.Count() > 0 {
+			continue
+		}
+		if !errors.Is(err, signals.ErrDontCloseSpider) {
+			return nil
+		}
+		
+		select {
+		case <-time.After(idleInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// shutdown 优雅关闭：不再出队新请求，在超时时间内等待进行中的请求及其结果处理完成
+func (e *Engine) shutdown(cancelWorkers context.CancelFunc) {
+	timeout := e.settings.ShutdownTimeout
+	fmt.Printf("🛑 正在关闭：停止调度新请求，等待进行中的请求完成（最长 %v）...\n", timeout)
+	
+	cancelWorkers()
+	
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		e.stopResultWorkers()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		fmt.Printf("✅ 进行中的请求已完成，剩余 %d 个请求未处理\n", e.scheduler.Size())
+	case <-time.After(timeout):
+		fmt.Printf("⚠️  等待超时，放弃仍在进行中的请求\n")
+		e.abandonResults()
+	}
+}
+
+// abandonResults 停止接受结果：之后完成的下载和产出的结果都被丢弃，
+// 等待正在处理的结果完成后返回，此后可以安全关闭管道和中间件
+func (e *Engine) abandonResults() {
+	e.abandoned.Store(true)
+	e.stopOnce.Do(func() { close(e.resultsStop) })
+	e.resultWg.Wait()
+}
+
 // startResultWorkers 启动结果处理协程池
-func (e *Engine) startResultWorkers(ctx context.Context) {
+func (e *Engine) startResultWorkers() {
 	for i := 0; i < e.resultWorkers; i++ {
 		e.resultWg.Add(1)
 		go func(workerID int) {
@@ -192,13 +569,22 @@ func (e *Engine) startResultWorkers(ctx context.Context) {
 			
 			for {
 				select {
-				case <-ctx.Done():
-					return
-				case result, ok := <-e.resultPool:
-					if !ok {
-						return // channel已关闭
-					}
+				case result := <-e.resultPool:
 					e.processResult(result)
+					e.inflight.Done()
+				case <-e.resultsStop:
+					// 处理完池中剩余的结果后退出，关闭超时时直接丢弃
+					for {
+						select {
+						case result := <-e.resultPool:
+							if !e.abandoned.Load() {
+								e.processResult(result)
+							}
+							e.inflight.Done()
+						default:
+							return
+						}
+					}
 				}
 			}
 		}(i)
@@ -206,341 +592,439 @@ func (e *Engine) startResultWorkers(ctx context.Context) {
 	fmt.Printf("🚀 Started %d result workers for yield processing\n", e.resultWorkers)
 }
 
+// stopResultWorkers 等待已产出的结果全部送入结果池后停止结果处理协程
+func (e *Engine) stopResultWorkers() {
+	e.sendWg.Wait()
+	e.stopOnce.Do(func() { close(e.resultsStop) })
+	e.resultWg.Wait()
+}
 
 // worker 工作协程
 func (e *Engine) worker(ctx context.Context, s spider.Spider) {
 	defer e.wg.Done()
 	
-	emptyCount := 0
-	maxEmptyCount := 100 // 连续空闲100次后退出（约100ms）
-	
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			req := e.scheduler.Dequeue()
-			if req == nil {
-				// 没有更多请求，检查是否应该退出
-				if e.scheduler.Empty() {
-					emptyCount++
-					if emptyCount >= maxEmptyCount {
-						// 连续空闲足够长时间，可能所有yield请求都处理完了
-						return
-					}
-				} else {
-					emptyCount = 0 // 重置计数器
+		}
+		
+		// 优先放行下载槽已空闲的暂存请求
+		req, slotKey := e.slots.NextReady()
+		if req == nil && e.slots.Held() < e.maxHeld {
+			req = e.scheduler.Dequeue()
+			if req != nil {
+				slotKey = e.slots.SlotKey(req)
+				if !e.slots.TryAcquire(slotKey) {
+					// 下载槽已满或未到下载间隔，暂存后继续处理其他请求
+					e.slots.Hold(slotKey, req)
+					continue
 				}
-				// 短暂等待后继续
-				time.Sleep(1 * time.Millisecond)
-				continue
 			}
-			
-			emptyCount = 0 // 有请求时重置计数器
-			// 直接处理请求（已经在协程中）
-			e.processRequest(req, s)
 		}
+		
+		if req == nil {
+			// 暂时没有可处理的请求，等待新请求入队、下载槽释放或引擎结束
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.wakeup:
+			case <-time.After(10 * time.Millisecond):
+			}
+			continue
+		}
+		
+		// 处理请求，解析结果已计入在途数后再释放该请求
+		e.processRequest(req, s, slotKey)
+		e.inflight.Done()
+	}
+}
+
+// releaseSlot 释放下载槽并唤醒等待的工作协程
+func (e *Engine) releaseSlot(slotKey string) {
+	e.slots.Release(slotKey)
+	e.wakeupWorker()
+}
+
+// wakeupWorker 唤醒一个等待的工作协程
+func (e *Engine) wakeupWorker() {
+	select {
+	case e.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// interceptDownload 依次询问下载拦截中间件，返回第一个非nil响应
+func (e *Engine) interceptDownload(req *request.Request) *response.Response {
+	for _, mw := range e.middlewares {
+		if im, ok := mw.(middleware.DownloadInterceptor); ok {
+			if resp := im.InterceptDownload(req); resp != nil {
+				return resp
+			}
+		}
+	}
+	return nil
+}
+
+// finishDownload 通知中间件请求处理结束，清理下载拦截时为请求保存的状态
+func (e *Engine) finishDownload(req *request.Request) {
+	for _, mw := range e.middlewares {
+		if fm, ok := mw.(middleware.DownloadFinisher); ok {
+			fm.DownloadFinished(req)
+		}
+	}
+}
+
+// schedule 请求入队并计入在途数
+func (e *Engine) schedule(req *request.Request) {
+	if e.closed.Load() {
+		return
+	}
+	
+	// 先计数再入队，避免请求被取走处理完时计数提前归零
+	e.inflight.Add(1)
+	if err := scheduler.TryEnqueue(e.scheduler, req); err != nil {
+		// 请求被调度器丢弃（如重复请求）
+		e.inflight.Done()
+		reason := "scheduler"
+		if errors.Is(err, scheduler.ErrUnserializable) {
+			// 磁盘队列无法保存闭包回调，丢弃的请求不会被爬取，必须让用户看到
+			reason = "unserializable"
+			fmt.Printf("❌ 请求无法保存到磁盘队列，已丢弃（使用JOBDIR时回调须为爬虫方法，不能是闭包）: %v\n", err)
+		}
+		e.signals.Send(signals.Event{Signal: signals.RequestDropped, Request: req, Reason: reason})
+		return
+	}
+	e.scheduled.Add(1)
+	e.signals.Send(signals.Event{Signal: signals.RequestScheduled, Request: req})
+	
+	select {
+	case e.wakeup <- struct{}{}:
+	default:
 	}
 }
 
 // processRequest 处理单个请求
-func (e *Engine) processRequest(req *request.Request, s spider.Spider) {
-	e.updateStats("request_total", 1)
+// 下载完成（或请求被中间件丢弃）后释放下载槽
+func (e *Engine) processRequest(req *request.Request, s spider.Spider, slotKey string) {
+	released := false
+	release := func() {
+		if !released {
+			released = true
+			e.releaseSlot(slotKey)
+		}
+	}
+	defer release()
+	
+	domain := requestDomain(req.URL)
+	e.updateStats("downloader/request_count", 1)
+	e.updateStats("domain/"+domain+"/request_count", 1)
+	
+	// 引擎维护的Meta：深度和重试次数
+	if req.Meta == nil {
+		req.Meta = make(map[string]interface{})
+	}
+	if _, exists := req.Meta[request.MetaDepth]; !exists {
+		req.Meta[request.MetaDepth] = 0
+	}
+	req.Meta[request.MetaRetryTimes] = req.RetryTimes
 	
 	// 应用下载中间件
 	for _, mw := range e.middlewares {
-		req = mw.ProcessRequest(req)
-		if req == nil {
+		processed := mw.ProcessRequest(req)
+		if processed == nil {
+			e.signals.Send(signals.Event{Signal: signals.RequestDropped, Request: req, Reason: "middleware"})
 			return
 		}
+		req = processed
 	}
 	
-	// 下载
-	resp, err := e.downloader.Download(req)
+	// 下载，拦截中间件（如HTTP缓存）提供响应时跳过下载
+	var resp *response.Response
+	var err error
+	defer e.finishDownload(req)
+	if resp = e.interceptDownload(req); resp != nil {
+		released = true
+		e.slots.ReleaseUnused(slotKey)
+		e.wakeupWorker()
+	} else {
+		resp, err = e.downloader.Download(req)
+		release()
+	}
+	if e.abandoned.Load() {
+		// 关闭等待已超时，中间件和管道可能已关闭
+		return
+	}
 	if err != nil {
-		e.updateStats("request_failed", 1)
-		fmt.Printf("Download failed: %v\n", err)
+		e.updateStats("downloader/exception_count", 1)
+		e.updateStats("downloader/exception_type_count/"+exceptionType(err), 1)
+		e.updateStats("domain/"+domain+"/exception_count", 1)
+		
+		// 🚀 交给异常中间件处理，返回的请求重新调度（如重试）
+		if retryReq := e.processException(req, err); retryReq != nil {
+			e.reschedule(retryReq)
+			return
+		}
+		
+		e.failRequest(req, err, s)
 		return
 	}
 	
-	e.updateStats("request_success", 1)
+	e.updateStats("response_received_count", 1)
+	e.updateStats(fmt.Sprintf("downloader/response_status_count/%d", resp.StatusCode), 1)
+	e.updateStats("downloader/response_bytes", int64(len(resp.Body)))
+	e.updateStats("domain/"+domain+"/response_count", 1)
+	e.updateStats("domain/"+domain+"/response_bytes", int64(len(resp.Body)))
+	e.signals.Send(signals.Event{Signal: signals.ResponseReceived, Request: req, Response: resp})
 	
 	// 应用响应中间件
 	for _, mw := range e.middlewares {
+		if rm, ok := mw.(middleware.RescheduleMiddleware); ok {
+			retryReq, failErr := rm.RescheduleResponse(req, resp)
+			if retryReq != nil {
+				e.reschedule(retryReq)
+				return
+			}
+			if failErr != nil {
+				e.failRequest(req, failErr, s)
+				return
+			}
+		}
+		
 		resp = mw.ProcessResponse(req, resp)
 		if resp == nil {
 			return
 		}
 	}
 	
-	// 解析响应
-	results := s.Parse(resp)
-	
-	// 🚀 协程模式处理解析结果 - 关键优化点！
-	e.processResultsConcurrently(results)
+	// 🚀 经过爬虫中间件后，协程模式处理解析结果 - 关键优化点！
+	e.processResultsConcurrently(e.scrape(req, resp, s))
 }
 
-// processRequestAsync 异步处理单个请求
-func (e *Engine) processRequestAsync(req *request.Request, s spider.Spider) {
-	e.updateStats("request_total", 1)
+// failRequest 请求最终失败（下载出错或重试耗尽）：计入失败并调用请求的错误回调
+func (e *Engine) failRequest(req *request.Request, err error, s spider.Spider) {
+	e.updateStats("request_failed_count", 1)
+	fmt.Printf("Request failed: %v\n", err)
 	
-	// 应用下载中间件
-	for _, mw := range e.middlewares {
-		req = mw.ProcessRequest(req)
-		if req == nil {
-			return
+	if errback := e.resolveErrback(req, s); errback != nil {
+		results, cbErr := callSpider(func() []interface{} { return errback(req, err) })
+		e.processResultsConcurrently(e.processSpiderResults(nil, results, cbErr, s))
+	}
+}
+
+// scrape 经过爬虫中间件调用请求的回调解析响应
+func (e *Engine) scrape(req *request.Request, resp *response.Response, s spider.Spider) []interface{} {
+	for _, mw := range e.spiderMiddlewares {
+		if err := mw.ProcessSpiderInput(resp, s); err != nil {
+			return e.processSpiderResults(resp, nil, err, s)
 		}
 	}
 	
-	// 🚀 异步下载
-	resultChan := e.downloader.DownloadAsync(req)
-	
-	// 异步处理下载结果
-	go func() {
-		asyncResult := <-resultChan
-		
-		if asyncResult.Error != nil {
-			e.updateStats("request_failed", 1)
-			fmt.Printf("Async download failed: %v\n", asyncResult.Error)
-			return
-		}
-		
-		e.updateStats("request_success", 1)
-		
-		resp := asyncResult.Response
-		
-		// 应用响应中间件
-		for _, mw := range e.middlewares {
-			resp = mw.ProcessResponse(req, resp)
-			if resp == nil {
-				return
-			}
+	// 按请求回调解析响应
+	callback := e.resolveCallback(req, s)
+	results, err := callSpider(func() []interface{} { return callback(resp) })
+	return e.processSpiderResults(resp, results, err, s)
+}
+
+// callSpider 调用爬虫回调，将panic转换为错误
+func callSpider(fn func() []interface{}) (results []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("spider callback panic: %v", r)
 		}
-		
-		// 解析响应
-		results := s.Parse(resp)
-		
-		// 🚀 协程模式处理解析结果
-		e.processResultsConcurrently(results)
 	}()
+	return fn(), nil
 }
 
-// processBatchRequests 批量异步处理请求
-func (e *Engine) processBatchRequests(reqs []*request.Request, s spider.Spider) {
-	if len(reqs) == 0 {
-		return
+// processSpiderResults 处理回调的结果和异常：结果中的error值与回调错误一同交给异常中间件，
+// 其余结果及异常中间件返回的结果设置深度后经过爬虫中间件的输出处理，最后过滤站外请求
+func (e *Engine) processSpiderResults(resp *response.Response, results []interface{}, err error, s spider.Spider) []interface{} {
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
 	}
 	
-	fmt.Printf("🚀 开始批量异步处理 %d 个请求\n", len(reqs))
-	
-	// 应用下载中间件
-	validReqs := make([]*request.Request, 0, len(reqs))
-	for _, req := range reqs {
-		e.updateStats("request_total", 1)
-		
-		for _, mw := range e.middlewares {
-			req = mw.ProcessRequest(req)
-			if req == nil {
-				break
-			}
-		}
-		
-		if req != nil {
-			validReqs = append(validReqs, req)
+	output := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		if resultErr, ok := result.(error); ok {
+			errs = append(errs, resultErr)
+			continue
 		}
+		output = append(output, result)
 	}
 	
-	if len(validReqs) == 0 {
-		return
+	for _, err := range errs {
+		output = append(output, e.processSpiderException(resp, err, s)...)
 	}
 	
-	// 🚀 批量异步下载 - 先发送所有请求
-	resultChan := e.downloader.DownloadBatch(validReqs)
-	
-	// 🚀 异步处理所有响应
-	go func() {
-		for asyncResult := range resultChan {
-			if asyncResult.Error != nil {
-				e.updateStats("request_failed", 1)
-				fmt.Printf("Batch download failed for %s: %v\n", asyncResult.Request.URL, asyncResult.Error)
-				continue
-			}
-			
-			e.updateStats("request_success", 1)
-			
-			resp := asyncResult.Response
-			req := asyncResult.Request
-			
-			// 应用响应中间件
-			for _, mw := range e.middlewares {
-				resp = mw.ProcessResponse(req, resp)
-				if resp == nil {
-					break
-				}
-			}
-			
-			if resp != nil {
-				// 解析响应
-				results := s.Parse(resp)
-				
-				// 🚀 协程模式处理解析结果
-				e.processResultsConcurrently(results)
+	// 由响应产生的请求深度加一
+	if resp != nil {
+		depth := resp.Depth()
+		for _, result := range output {
+			if newReq, ok := result.(*request.Request); ok {
+				newReq.SetMeta(request.MetaDepth, depth+1)
 			}
 		}
-		fmt.Printf("✅ 批量异步处理完成\n")
-	}()
-}
-
-// processResultsConcurrently 并发处理解析结果
-func (e *Engine) processResultsConcurrently(results []interface{}) {
-	if len(results) == 0 {
-		return
 	}
 	
-	// 🚀 分离请求和数据项
-	requests := make([]*request.Request, 0)
-	items := make([]interface{}, 0)
+	for _, mw := range e.spiderMiddlewares {
+		output = mw.ProcessSpiderOutput(resp, output, s)
+	}
+	return e.offsite.ProcessSpiderOutput(resp, output, s)
+}
+
+// processSpiderException 依次调用爬虫中间件处理异常，都未处理时记录异常
+func (e *Engine) processSpiderException(resp *response.Response, err error, s spider.Spider) []interface{} {
+	e.signals.Send(signals.Event{Signal: signals.SpiderError, Spider: s, Response: resp, Err: err})
 	
-	for _, result := range results {
-		switch r := result.(type) {
-		case *request.Request:
-			requests = append(requests, r)
-		default:
-			items = append(items, result)
+	for _, mw := range e.spiderMiddlewares {
+		if results := mw.ProcessSpiderException(resp, err, s); results != nil {
+			return results
 		}
 	}
 	
-	// 🚀 批量异步处理请求（如果有多个请求）
-	if len(requests) > 1 {
-		fmt.Printf("🚀 检测到 %d 个请求，启用批量异步模式\n", len(requests))
-		// 直接批量处理，不通过结果池
-		e.batchWg.Add(1)
-		go func() {
-			defer e.batchWg.Done()
-			e.processBatchRequestsDirectly(requests)
-		}()
+	e.updateStats("spider_exceptions/count", 1)
+	e.updateStats("spider_exceptions/"+exceptionType(err), 1)
+	if resp != nil {
+		fmt.Printf("❌ 爬虫解析出错 %s: %v\n", resp.URL, err)
 	} else {
-		// 单个请求或无请求，使用原有逻辑
-		for _, req := range requests {
-			select {
-			case e.resultPool <- req:
-				// 成功发送到协程池
-			default:
-				// 协程池满时，异步发送避免阻塞
-				go func(r interface{}) {
-					e.resultPool <- r
-				}(req)
-			}
+		fmt.Printf("❌ 爬虫错误回调出错: %v\n", err)
+	}
+	return nil
+}
+
+// resolveCallback 确定请求的回调：函数回调 > 回调名称 > Meta["callback"] > spider.Parse
+func (e *Engine) resolveCallback(req *request.Request, s spider.Spider) response.Callback {
+	if req.CallbackFunc != nil {
+		if cb, ok := req.CallbackFunc.(func(*response.Response) []interface{}); ok {
+			return cb
 		}
+		fmt.Printf("⚠️  无效的回调类型 %T: %s\n", req.CallbackFunc, req.URL)
 	}
 	
-	// 🚀 处理数据项
-	for _, item := range items {
-		select {
-		case e.resultPool <- item:
-			// 成功发送到协程池
-		default:
-			// 协程池满时，异步发送避免阻塞
-			go func(r interface{}) {
-				e.resultPool <- r
-			}(item)
+	name := req.Callback
+	if name == "" {
+		name, _ = req.Meta["callback"].(string)
+	}
+	if name != "" {
+		if cb := spider.LookupCallback(s, name); cb != nil {
+			return cb
 		}
+		fmt.Printf("⚠️  爬虫 %s 没有回调方法 %s，使用Parse: %s\n", s.Name(), name, req.URL)
 	}
+	
+	return s.Parse
 }
 
-// processBatchRequestsDirectly 直接批量处理请求（绕过结果池）
-func (e *Engine) processBatchRequestsDirectly(requests []*request.Request) {
-	if len(requests) == 0 {
-		return
+// resolveErrback 确定请求的错误回调，没有设置时返回nil
+func (e *Engine) resolveErrback(req *request.Request, s spider.Spider) response.Errback {
+	if req.ErrbackFunc != nil {
+		if eb, ok := req.ErrbackFunc.(func(*request.Request, error) []interface{}); ok {
+			return eb
+		}
+		fmt.Printf("⚠️  无效的错误回调类型 %T: %s\n", req.ErrbackFunc, req.URL)
 	}
 	
-	// 应用下载中间件
-	validReqs := make([]*request.Request, 0, len(requests))
-	for _, req := range requests {
-		e.updateStats("request_total", 1)
-		
-		for _, mw := range e.middlewares {
-			req = mw.ProcessRequest(req)
-			if req == nil {
-				break
-			}
+	if req.Errback != "" {
+		if eb := spider.LookupErrback(s, req.Errback); eb != nil {
+			return eb
 		}
-		
-		if req != nil {
-			validReqs = append(validReqs, req)
+		fmt.Printf("⚠️  爬虫 %s 没有错误回调方法 %s: %s\n", s.Name(), req.Errback, req.URL)
+	}
+	
+	return nil
+}
+
+// processException 依次调用异常中间件，返回需要重新调度的请求
+func (e *Engine) processException(req *request.Request, err error) *request.Request {
+	for _, mw := range e.middlewares {
+		if em, ok := mw.(middleware.ExceptionMiddleware); ok {
+			if newReq := em.ProcessException(req, err); newReq != nil {
+				return newReq
+			}
 		}
 	}
+	return nil
+}
+
+// reschedule 重新调度请求，Meta中带有重试延迟时延迟入队
+func (e *Engine) reschedule(req *request.Request) {
+	delay, _ := req.Meta[middleware.RetryDelayMetaKey].(time.Duration)
+	delete(req.Meta, middleware.RetryDelayMetaKey)
 	
-	if len(validReqs) == 0 {
+	if delay <= 0 {
+		e.schedule(req)
 		return
 	}
 	
-	fmt.Printf("🚀 批量异步发送 %d 个请求\n", len(validReqs))
-	
-	// 🚀 打印即将发送的请求URL
-	for i, req := range validReqs {
-		fmt.Printf("📤 准备发送请求 %d: %s\n", i+1, req.URL)
+	// 等待期间也计入在途数，防止引擎提前结束
+	e.delayedMu.Lock()
+	defer e.delayedMu.Unlock()
+	if e.delayed == nil {
+		e.delayed = make(map[*request.Request]*time.Timer)
 	}
+	e.inflight.Add(1)
+	e.delayed[req] = time.AfterFunc(delay, func() {
+		e.delayedMu.Lock()
+		if _, pending := e.delayed[req]; pending {
+			delete(e.delayed, req)
+			e.schedule(req)
+		}
+		e.delayedMu.Unlock()
+		e.inflight.Done()
+	})
+}
+
+// closeDelayed 停止接受新请求，并把仍在等待退避的重试请求直接放回调度器
+//
+// 与延迟重试的定时回调持有同一把锁，保证每个请求要么已正常入队，要么在这里入队。
+// 请求指纹已记录在去重过滤器中，因此以DontFilter入队。
+func (e *Engine) closeDelayed() {
+	e.delayedMu.Lock()
+	defer e.delayedMu.Unlock()
 	
-	// 🚀 批量异步下载 - 先发送所有请求
-	fmt.Printf("🔄 调用下载器批量异步下载...\n")
-	resultChan := e.downloader.DownloadBatch(validReqs)
-	fmt.Printf("✅ 下载器返回结果通道，开始监听响应...\n")
-	
-	// 🚀 异步处理所有响应
-	processedCount := 0
-	fmt.Printf("🔍 开始循环监听结果通道...\n")
-	for asyncResult := range resultChan {
-		processedCount++
-		fmt.Printf("🔍 处理第 %d 个异步结果: %s\n", processedCount, asyncResult.Request.URL)
-		
-		if asyncResult.Error != nil {
-			e.updateStats("request_failed", 1)
-			fmt.Printf("❌ 批量下载失败 %s: %v\n", asyncResult.Request.URL, asyncResult.Error)
-			continue
+	e.closed.Store(true)
+	for req, timer := range e.delayed {
+		if timer.Stop() {
+			e.inflight.Done()
 		}
-		
-		if asyncResult.Response == nil {
-			e.updateStats("request_failed", 1)
-			fmt.Printf("❌ 批量下载响应为空 %s\n", asyncResult.Request.URL)
+		delete(e.delayed, req)
+		req.DontFilter = true
+		e.scheduler.Enqueue(req)
+	}
+}
+
+// processResultsConcurrently 并发处理解析结果
+func (e *Engine) processResultsConcurrently(results []interface{}) {
+	// 🚀 请求与数据项统一交给结果协程池处理，请求会经由调度器重新分发给工作协程
+	if e.abandoned.Load() {
+		return
+	}
+	for _, result := range results {
+		if result == nil {
 			continue
 		}
 		
-		e.updateStats("request_success", 1)
-		fmt.Printf("✅ 批量下载成功 %s (状态码: %d)\n", asyncResult.Request.URL, asyncResult.Response.StatusCode)
-		
-		resp := asyncResult.Response
-		req := asyncResult.Request
-		
-		// 应用响应中间件
-		for _, mw := range e.middlewares {
-			resp = mw.ProcessResponse(req, resp)
-			if resp == nil {
-				break
-			}
-		}
-		
-		if resp != nil {
-			// 🚀 获取spider实例并解析响应
-			e.spiderMutex.RLock()
-			currentSpider := e.currentSpider
-			e.spiderMutex.RUnlock()
-			
-			if currentSpider != nil {
-				fmt.Printf("✅ 批量异步响应处理: %s (状态码: %d)\n", resp.URL, resp.StatusCode)
-				
-				// 🚀 使用spider解析响应
-				results := currentSpider.Parse(resp)
-				
-				// 🚀 递归处理解析结果
-				e.processResultsConcurrently(results)
-			} else {
-				fmt.Printf("⚠️ 无法获取spider实例，跳过响应解析: %s\n", resp.URL)
-			}
+		// 先计入在途数，保证结果处理完之前引擎不会判定结束
+		e.inflight.Add(1)
+		select {
+		case e.resultPool <- result:
+			// 成功发送到协程池
+		default:
+			// 协程池满时，异步发送避免阻塞
+			e.sendWg.Add(1)
+			go func(r interface{}) {
+				defer e.sendWg.Done()
+				select {
+				case e.resultPool <- r:
+				case <-e.resultsStop:
+					// 结果协程已退出（关闭超时），丢弃结果
+					e.inflight.Done()
+				}
+			}(result)
 		}
 	}
-	fmt.Printf("🔍 结果通道已关闭，退出循环\n")
-	fmt.Printf("✅ 批量异步处理完成，共处理了 %d 个响应\n", processedCount)
 }
 
 // processResult 处理单个结果
@@ -548,7 +1032,7 @@ func (e *Engine) processResult(result interface{}) {
 	switch r := result.(type) {
 	case *request.Request:
 		// 直接入队新请求（已在协程池中）
-		e.scheduler.Enqueue(r)
+		e.schedule(r)
 	case map[string]interface{}:
 		// 直接处理数据项（已在协程池中）
 		e.processItem(r)
@@ -560,20 +1044,24 @@ func (e *Engine) processResult(result interface{}) {
 
 // processItem 处理数据项
 func (e *Engine) processItem(item map[string]interface{}) {
-	e.updateStats("items_scraped", 1)
+	e.updateStats("item_scraped_count", 1)
 	
 	// 通过管道处理数据
 	for _, p := range e.pipelines {
-		item = p.ProcessItem(item)
-		if item == nil {
+		processed := p.ProcessItem(item)
+		if processed == nil {
+			e.updateStats("item_dropped_count", 1)
+			e.signals.Send(signals.Event{Signal: signals.ItemDropped, Item: item})
 			return
 		}
+		item = processed
 	}
+	e.signals.Send(signals.Event{Signal: signals.ItemScraped, Item: item})
 }
 
 // processAnyItem 处理任意类型的数据项
 func (e *Engine) processAnyItem(item interface{}) {
-	e.updateStats("items_scraped", 1)
+	e.updateStats("item_scraped_count", 1)
 	
 	// 🚀 将任意类型转换为map[string]interface{}供管道处理
 	var mapItem map[string]interface{}
@@ -593,43 +1081,68 @@ func (e *Engine) processAnyItem(item interface{}) {
 	for _, p := range e.pipelines {
 		mapItem = p.ProcessItem(mapItem)
 		if mapItem == nil {
+			e.updateStats("item_dropped_count", 1)
+			e.signals.Send(signals.Event{Signal: signals.ItemDropped, Item: item})
 			return
 		}
 	}
+	e.signals.Send(signals.Event{Signal: signals.ItemScraped, Item: item})
 }
 
-// updateStats 更新统计信息
+// updateStats 更新计数并检查自动关闭条件
 func (e *Engine) updateStats(key string, value int64) {
-	e.stats.mu.Lock()
-	defer e.stats.mu.Unlock()
-	
-	switch key {
-	case "request_total":
-		e.stats.RequestsTotal += value
-	case "request_success":
-		e.stats.RequestsSuccess += value
-	case "request_failed":
-		e.stats.RequestsFailed += value
-	case "items_scraped":
-		e.stats.ItemsScraped += value
+	e.stats.Inc(key, value)
+	e.checkCloseSpider(key, value)
+}
+
+// collectComponentStats 将组件统计（如去重数、按原因统计的重试次数）合并到统计收集器
+func (e *Engine) collectComponentStats() {
+	providers := []interface{}{e.scheduler, e.slots}
+	for _, ext := range e.extensions {
+		providers = append(providers, ext)
+	}
+	for _, mw := range e.middlewares {
+		providers = append(providers, mw)
+	}
+	for _, mw := range e.spiderMiddlewares {
+		providers = append(providers, mw)
+	}
+	providers = append(providers, e.offsite)
+	for _, p := range providers {
+		if sp, ok := p.(statsProvider); ok {
+			for key, value := range sp.Stats() {
+				e.stats.Set(key, value)
+			}
+		}
 	}
 }
 
 // printStats 打印统计信息
 func (e *Engine) printStats() {
-	e.stats.mu.RLock()
-	defer e.stats.mu.RUnlock()
-	
-	duration := time.Since(e.stats.StartTime)
-	
 	fmt.Println("\n=== Crawl Stats ===")
-	fmt.Printf("Duration: %v\n", duration)
-	fmt.Printf("Requests Total: %d\n", e.stats.RequestsTotal)
-	fmt.Printf("Requests Success: %d\n", e.stats.RequestsSuccess)
-	fmt.Printf("Requests Failed: %d\n", e.stats.RequestsFailed)
-	fmt.Printf("Items Scraped: %d\n", e.stats.ItemsScraped)
-	
-	if duration.Seconds() > 0 {
-		fmt.Printf("Requests/sec: %.2f\n", float64(e.stats.RequestsTotal)/duration.Seconds())
+	stats.Print(e.stats)
+	
+	if elapsed, ok := e.stats.Get("elapsed_time_seconds").(float64); ok && elapsed > 0 {
+		fmt.Printf("Requests/sec: %.2f\n", float64(e.stats.GetInt("downloader/request_count"))/elapsed)
+	}
+}
+
+// requestDomain 请求URL的主机名，用于按域名统计
+func requestDomain(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Hostname() == "" {
+		return "unknown"
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// exceptionType 错误的类型名，取错误链最内层的错误，如 *net.DNSError
+func exceptionType(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
 	}
 }
\ No newline at end of file
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightCounter 在途请求计数器
+//
+// 请求入队时加一，下载、解析完成且产出的结果都已计入后减一；
+// 结果进入结果池时加一，处理完成后减一。计数归零即表示爬取真正结束。
+type inflightCounter struct {
+	mu    sync.Mutex
+	count int64
+	zero  chan struct{}
+}
+
+// newInflightCounter 创建在途请求计数器
+func newInflightCounter() *inflightCounter {
+	return &inflightCounter{
+		zero: make(chan struct{}, 1),
+	}
+}
+
+// Add 增加在途数
+func (c *inflightCounter) Add(n int64) {
+	c.mu.Lock()
+	c.count += n
+	if c.count < 0 {
+		c.mu.Unlock()
+		panic("engine: negative inflight count")
+	}
+	reachedZero := c.count == 0
+	c.mu.Unlock()
+
+	if reachedZero {
+		select {
+		case c.zero <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Done 减少一个在途数
+func (c *inflightCounter) Done() {
+	c.Add(-1)
+}
+
+// Count 当前在途数
+func (c *inflightCounter) Count() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// Wait 阻塞直到在途数归零，ctx取消时返回ctx的错误
+func (c *inflightCounter) Wait(ctx context.Context) error {
+	for c.Count() != 0 {
+		select {
+		case <-c.zero:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
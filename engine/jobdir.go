@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"scrago/scheduler"
+)
+
+// JOBDIR 中的文件
+const (
+	jobQueueFile = "requests.queue"
+	jobSeenFile  = "requests.seen"
+	jobStatsFile = "stats.json"
+)
+
+// SetJobDir 设置持久化目录：待处理请求队列、去重指纹和统计信息都保存在该目录，
+// 使用相同目录再次运行时从上次停止的地方继续
+//
+// 队列中的回调按方法名保存，只有以爬虫方法为回调的请求能暂停后恢复；
+// 回调是闭包的请求无法入队，以unserializable原因丢弃并计入scheduler/unserializable。
+// order为磁盘队列的出队顺序（SCHEDULER_DISK_QUEUE），为空时按优先级出队，恢复时须使用相同的顺序。
+func (e *Engine) SetJobDir(dir string, order scheduler.QueueOrder) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create job directory failed: %w", err)
+	}
+
+	// 默认按优先级出队，优先级相同时保持先进先出
+	if order == "" {
+		order = scheduler.OrderPriority
+	}
+	queue, err := scheduler.NewDiskScheduler(filepath.Join(dir, jobQueueFile), order, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	store, err := scheduler.NewFileStore(filepath.Join(dir, jobSeenFile))
+	if err != nil {
+		queue.Close()
+		return err
+	}
+
+	e.scheduler = scheduler.NewDupeFilterScheduler(queue, scheduler.NewRFPDupeFilter(store))
+	e.jobDir = dir
+	return nil
+}
+
+// JobDir 返回持久化目录
+func (e *Engine) JobDir() string {
+	return e.jobDir
+}
+
+// loadJobStats 从JOBDIR恢复计数类统计
+func (e *Engine) loadJobStats() error {
+	data, err := os.ReadFile(filepath.Join(e.jobDir, jobStatsFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	for key, value := range saved {
+		count, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		e.stats.Inc(key, int64(count))
+	}
+	return nil
+}
+
+// saveJobStats 保存计数类统计到JOBDIR，时间、关闭原因等不保存
+func (e *Engine) saveJobStats() error {
+	saved := make(map[string]int64)
+	for key, value := range e.stats.All() {
+		if count, ok := value.(int64); ok {
+			saved[key] = count
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免中途退出留下损坏的文件
+	path := filepath.Join(e.jobDir, jobStatsFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
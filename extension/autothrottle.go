@@ -0,0 +1,256 @@
+package extension
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"scrago/downloader"
+	"scrago/request"
+	"scrago/response"
+	"scrago/signals"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AutoThrottleOptions 自动限速配置
+type AutoThrottleOptions struct {
+	StartDelay        time.Duration // 新域名的初始下载延迟
+	MinDelay          time.Duration // 最小下载延迟
+	MaxDelay          time.Duration // 最大下载延迟
+	TargetConcurrency float64       // 每个域名期望的平均并发请求数
+	Debug             bool          // 打印每个响应的限速状态
+}
+
+// DefaultAutoThrottleOptions 默认自动限速配置
+func DefaultAutoThrottleOptions() AutoThrottleOptions {
+	return AutoThrottleOptions{
+		StartDelay:        5 * time.Second,
+		MinDelay:          0,
+		MaxDelay:          60 * time.Second,
+		TargetConcurrency: 1.0,
+	}
+}
+
+// ThrottleState 单个下载槽的限速状态
+type ThrottleState struct {
+	Delay       time.Duration
+	Concurrency int
+	Latency     time.Duration // 响应耗时的滑动平均
+	Responses   int64
+	Throttled   int64 // 429/503 响应
+}
+
+// ErrorRate 被限流响应占全部响应的比例
+func (s ThrottleState) ErrorRate() float64 {
+	if s.Responses == 0 {
+		return 0
+	}
+	return float64(s.Throttled) / float64(s.Responses)
+}
+
+// AutoThrottle 自动限速扩展，由AUTOTHROTTLE_ENABLED启用
+//
+// 订阅response_received信号，根据每个下载槽（域名）的响应耗时调整下载延迟，使平均并发请求数趋近TargetConcurrency：
+// 目标延迟 = 响应耗时 / TargetConcurrency，新延迟取当前延迟与目标延迟的平均值。
+// 响应正常时逐步提高并发上限；出现429或503时并发减半、延迟加倍，
+// 并遵守Retry-After响应头。延迟始终限制在 [MinDelay, MaxDelay] 之间。缓存命中的响应不参与调整。
+type AutoThrottle struct {
+	slots   *downloader.SlotManager
+	options AutoThrottleOptions
+	states  map[string]*throttleState
+	mutex   sync.Mutex
+}
+
+// throttleState 下载槽的内部限速状态
+type throttleState struct {
+	ThrottleState
+	maxConcurrency int // 下载槽原有的并发上限
+}
+
+// NewAutoThrottle 创建自动限速扩展
+func NewAutoThrottle(options AutoThrottleOptions) *AutoThrottle {
+	if options.TargetConcurrency <= 0 {
+		options.TargetConcurrency = 1.0
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = 60 * time.Second
+	}
+
+	return &AutoThrottle{
+		options: options,
+		states:  make(map[string]*throttleState),
+	}
+}
+
+// Open 新建的下载槽以StartDelay开始，并订阅响应信号
+func (a *AutoThrottle) Open(crawler Crawler) error {
+	a.slots = crawler.DownloadSlots()
+
+	startDelay := a.options.StartDelay
+	if startDelay < a.options.MinDelay {
+		startDelay = a.options.MinDelay
+	}
+	a.slots.SetDefaultDelay(startDelay)
+
+	crawler.Signals().Connect(signals.ResponseReceived, a.responseReceived)
+	fmt.Printf("🐢 自动限速: 目标并发 %.1f，延迟 %v ~ %v\n", a.options.TargetConcurrency,
+		a.options.MinDelay, a.options.MaxDelay)
+	return nil
+}
+
+// Close 关闭扩展
+func (a *AutoThrottle) Close() error {
+	return nil
+}
+
+func (a *AutoThrottle) responseReceived(event signals.Event) error {
+	if cached, _ := event.Response.Meta[request.MetaCached].(bool); cached {
+		return nil
+	}
+	a.ResponseDownloaded(a.slots.SlotKey(event.Request), event.Response)
+	return nil
+}
+
+// ResponseDownloaded 根据响应调整下载槽的延迟和并发上限
+func (a *AutoThrottle) ResponseDownloaded(slotKey string, resp *response.Response) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	state, exists := a.states[slotKey]
+	if !exists {
+		state = &throttleState{maxConcurrency: a.slots.Concurrency(slotKey)}
+		state.Concurrency = state.maxConcurrency
+		a.states[slotKey] = state
+	}
+
+	oldDelay := a.slots.Delay(slotKey)
+	delay := oldDelay
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		state.Responses++
+		state.Throttled++
+		a.observeLatency(state, resp)
+		delay, state.Concurrency = a.backoff(delay, retryAfter(resp), state.Concurrency)
+
+	default:
+		state.Responses++
+		latency := a.observeLatency(state, resp)
+
+		targetDelay := time.Duration(float64(latency) / a.options.TargetConcurrency)
+		newDelay := (delay + targetDelay) / 2
+		if newDelay < targetDelay {
+			newDelay = targetDelay
+		}
+		// 非200响应（如错误页）通常更快，不据此降低延迟
+		if resp.StatusCode == http.StatusOK || newDelay > delay {
+			delay = newDelay
+		}
+
+		target := int(math.Ceil(a.options.TargetConcurrency))
+		if target > state.maxConcurrency {
+			target = state.maxConcurrency
+		}
+		if state.Concurrency < target {
+			state.Concurrency++
+		} else if state.Concurrency > target {
+			state.Concurrency = target
+		}
+	}
+
+	delay = a.clamp(delay)
+	state.Delay = delay
+	a.slots.SetDelay(slotKey, delay)
+	a.slots.SetConcurrency(slotKey, state.Concurrency)
+
+	if a.options.Debug {
+		fmt.Printf("🐢 [autothrottle] slot: %s | status: %d | conc: %d | delay: %v (%+v) | latency: %v\n",
+			slotKey, resp.StatusCode, state.Concurrency, delay, delay-oldDelay, state.Latency)
+	}
+}
+
+// observeLatency 记录响应耗时，返回本次耗时
+func (a *AutoThrottle) observeLatency(state *throttleState, resp *response.Response) time.Duration {
+	latency, _ := resp.GetMeta(request.MetaDownloadLatency).(time.Duration)
+	if state.Latency == 0 {
+		state.Latency = latency
+	} else {
+		state.Latency = (state.Latency*7 + latency) / 8
+	}
+	return latency
+}
+
+// backoff 服务端过载时并发减半、延迟加倍
+func (a *AutoThrottle) backoff(delay, retryAfter time.Duration, concurrency int) (time.Duration, int) {
+	if delay <= 0 {
+		delay = a.options.MinDelay
+		if delay <= 0 {
+			delay = time.Second
+		}
+	} else {
+		delay *= 2
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+
+	concurrency /= 2
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return delay, concurrency
+}
+
+// clamp 将延迟限制在 [MinDelay, MaxDelay] 之间
+func (a *AutoThrottle) clamp(delay time.Duration) time.Duration {
+	if delay < a.options.MinDelay {
+		return a.options.MinDelay
+	}
+	if delay > a.options.MaxDelay {
+		return a.options.MaxDelay
+	}
+	return delay
+}
+
+// retryAfter 解析Retry-After响应头（秒数或HTTP日期）
+func retryAfter(resp *response.Response) time.Duration {
+	value := resp.Headers.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// States 各下载槽当前的限速状态
+func (a *AutoThrottle) States() map[string]ThrottleState {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	states := make(map[string]ThrottleState, len(a.states))
+	for key, state := range a.states {
+		states[key] = state.ThrottleState
+	}
+	return states
+}
+
+// Stats 按下载槽输出限速状态
+func (a *AutoThrottle) Stats() map[string]int64 {
+	stats := make(map[string]int64)
+	for key, state := range a.States() {
+		prefix := "autothrottle/" + key + "/"
+		stats[prefix+"delay_ms"] = state.Delay.Milliseconds()
+		stats[prefix+"concurrency"] = int64(state.Concurrency)
+		stats[prefix+"latency_ms"] = state.Latency.Milliseconds()
+		stats[prefix+"responses"] = state.Responses
+		stats[prefix+"throttled"] = state.Throttled
+		stats[prefix+"error_rate_pct"] = int64(state.ErrorRate() * 100)
+	}
+	return stats
+}
@@ -0,0 +1,44 @@
+package extension
+
+import (
+	"scrago/signals"
+	"scrago/stats"
+	"time"
+)
+
+// CoreStats 核心统计扩展，记录爬虫的开始时间、结束时间和运行时长
+type CoreStats struct {
+	stats     stats.Collector
+	startTime time.Time
+}
+
+// NewCoreStats 创建核心统计扩展
+func NewCoreStats() *CoreStats {
+	return &CoreStats{}
+}
+
+// Open 订阅爬虫开始和结束信号
+func (c *CoreStats) Open(crawler Crawler) error {
+	c.stats = crawler.Stats()
+	crawler.Signals().Connect(signals.SpiderOpened, c.spiderOpened)
+	crawler.Signals().Connect(signals.SpiderClosed, c.spiderClosed)
+	return nil
+}
+
+// Close 关闭扩展
+func (c *CoreStats) Close() error {
+	return nil
+}
+
+func (c *CoreStats) spiderOpened(event signals.Event) error {
+	c.startTime = time.Now()
+	c.stats.Set("start_time", c.startTime.Format(time.RFC3339))
+	return nil
+}
+
+func (c *CoreStats) spiderClosed(event signals.Event) error {
+	finishTime := time.Now()
+	c.stats.Set("finish_time", finishTime.Format(time.RFC3339))
+	c.stats.Set("elapsed_time_seconds", finishTime.Sub(c.startTime).Seconds())
+	return nil
+}
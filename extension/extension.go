@@ -0,0 +1,24 @@
+package extension
+
+import (
+	"scrago/downloader"
+	"scrago/signals"
+	"scrago/stats"
+)
+
+// Crawler 扩展可以使用的引擎功能，由engine.Engine实现
+type Crawler interface {
+	Signals() *signals.Manager
+	Stats() stats.Collector
+	DownloadSlots() *downloader.SlotManager
+	CloseSpider(reason string)
+}
+
+// Extension 扩展接口
+//
+// 引擎在打开管道之后按顺序调用Open，扩展在其中订阅信号、启动后台任务；
+// 爬虫关闭（spider_closed信号发送）之后按相反顺序调用Close。
+type Extension interface {
+	Open(crawler Crawler) error
+	Close() error
+}
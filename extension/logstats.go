@@ -0,0 +1,64 @@
+package extension
+
+import (
+	"fmt"
+	"scrago/stats"
+	"time"
+)
+
+// LogStats 定期打印爬取速度的扩展：已下载页面数、已抓取数据项数及每分钟速率
+type LogStats struct {
+	interval time.Duration
+	stats    stats.Collector
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewLogStats 创建爬取速度日志扩展，interval为0时使用60秒
+func NewLogStats(interval time.Duration) *LogStats {
+	if interval <= 0 {
+		interval = 60 * time.Second
+	}
+	return &LogStats{interval: interval}
+}
+
+// Open 启动定期打印
+func (l *LogStats) Open(crawler Crawler) error {
+	l.stats = crawler.Stats()
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.run()
+	return nil
+}
+
+// Close 停止定期打印
+func (l *LogStats) Close() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+	return nil
+}
+
+func (l *LogStats) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	perMinute := float64(time.Minute) / float64(l.interval)
+	var prevPages, prevItems int64
+	for {
+		select {
+		case <-ticker.C:
+			pages := l.stats.GetInt("response_received_count")
+			items := l.stats.GetInt("item_scraped_count")
+			fmt.Printf("📈 已爬取 %d 个页面（%.0f 页/分钟），已抓取 %d 个数据项（%.0f 项/分钟）\n",
+				pages, float64(pages-prevPages)*perMinute, items, float64(items-prevItems)*perMinute)
+			prevPages, prevItems = pages, items
+		case <-l.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package extension
+
+import (
+	"fmt"
+	"runtime"
+	"scrago/stats"
+	"time"
+)
+
+// CloseReasonMemUsage 内存超过上限时爬虫的关闭原因
+const CloseReasonMemUsage = "memusage_exceeded"
+
+// MemoryUsage 内存监控扩展
+//
+// 定期检查进程从系统获取的内存，超过警告值时打印一次警告，超过上限时关闭爬虫。
+// 记录 memusage/startup、memusage/max 等统计。
+type MemoryUsage struct {
+	limit    uint64 // 字节，0表示不限制
+	warning  uint64 // 字节，0表示不警告
+	interval time.Duration
+	crawler  Crawler
+	stats    stats.Collector
+	warned   bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMemoryUsage 创建内存监控扩展，interval为0时使用60秒
+func NewMemoryUsage(limitMB, warningMB int, interval time.Duration) *MemoryUsage {
+	if interval <= 0 {
+		interval = 60 * time.Second
+	}
+	return &MemoryUsage{
+		limit:    uint64(limitMB) << 20,
+		warning:  uint64(warningMB) << 20,
+		interval: interval,
+	}
+}
+
+// Open 记录启动时的内存并开始定期检查
+func (m *MemoryUsage) Open(crawler Crawler) error {
+	m.crawler = crawler
+	m.stats = crawler.Stats()
+	m.stats.Set("memusage/startup", int64(memoryUsage()))
+	if m.limit > 0 {
+		m.stats.Set("memusage/limit_mb", int64(m.limit>>20))
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run()
+	return nil
+}
+
+// Close 停止检查
+func (m *MemoryUsage) Close() error {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.done
+		m.stop = nil
+	}
+	m.check()
+	return nil
+}
+
+func (m *MemoryUsage) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// check 检查当前内存，超过警告值或上限时处理
+func (m *MemoryUsage) check() {
+	usage := memoryUsage()
+	m.stats.Max("memusage/max", int64(usage))
+
+	if m.limit > 0 && usage > m.limit {
+		if m.stats.GetInt("memusage/limit_reached") == 0 {
+			m.stats.Set("memusage/limit_reached", int64(1))
+			fmt.Printf("❌ 内存使用 %d MB 超过上限 %d MB，正在关闭爬虫\n", usage>>20, m.limit>>20)
+			m.crawler.CloseSpider(CloseReasonMemUsage)
+		}
+		return
+	}
+
+	if m.warning > 0 && usage > m.warning && !m.warned {
+		m.warned = true
+		m.stats.Set("memusage/warning_reached", int64(1))
+		fmt.Printf("⚠️  内存使用 %d MB 超过警告值 %d MB\n", usage>>20, m.warning>>20)
+	}
+}
+
+// memoryUsage 进程从系统获取的内存（字节）
+func memoryUsage() uint64 {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.Sys
+}
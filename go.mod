@@ -9,11 +9,11 @@ require (
 	github.com/andybalholm/brotli v1.2.0
 	github.com/antchfx/htmlquery v1.3.0
 	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
 )
 
 require (
 	github.com/andybalholm/cascadia v1.3.1 // indirect
 	github.com/antchfx/xpath v1.2.4 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
-	golang.org/x/text v0.13.0 // indirect
 )
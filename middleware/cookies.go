@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"scrago/cookies"
+	"scrago/request"
+	"scrago/response"
+	"sync"
+)
+
+// CookieMiddleware Cookie中间件
+//
+// 按RFC 6265保存和发送Cookie（域名、路径、过期时间、Secure及公共后缀规则）。
+// 请求Meta中cookiejar的值选择Cookie Jar，同一爬虫可以用不同的值维持多个登录会话；
+// dont_merge_cookies为true时该请求不使用Cookie Jar，只发送请求自带的Cookie。
+// Cookie Jar交给下载器使用，重定向过程中设置的Cookie同样会保存。
+type CookieMiddleware struct {
+	jars  *cookies.Jars
+	file  string
+	debug bool
+
+	stats map[string]int64
+	mutex sync.Mutex
+}
+
+// NewCookieMiddleware 创建Cookie中间件
+func NewCookieMiddleware() *CookieMiddleware {
+	return &CookieMiddleware{
+		jars:  cookies.NewJars(),
+		stats: make(map[string]int64),
+	}
+}
+
+// SetDebug 设置是否打印发送和收到的Cookie
+func (m *CookieMiddleware) SetDebug(debug bool) *CookieMiddleware {
+	m.debug = debug
+	return m
+}
+
+// SetFile 设置Netscape格式的Cookie文件：文件存在时导入到默认Cookie Jar，Close时导出
+func (m *CookieMiddleware) SetFile(path string) (*CookieMiddleware, error) {
+	m.file = path
+	if path == "" {
+		return m, nil
+	}
+
+	count, err := cookies.LoadFile(path, m.jars.Jar(cookies.DefaultJar))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("load cookies from %s: %w", path, err)
+	}
+	fmt.Printf("🍪 从 %s 导入 %d 个Cookie\n", path, count)
+	return m, nil
+}
+
+// Jars 返回所有Cookie Jar，可用于预先设置登录Cookie或导出
+func (m *CookieMiddleware) Jars() *cookies.Jars {
+	return m.jars
+}
+
+// ProcessRequest 为请求设置Cookie Jar
+func (m *CookieMiddleware) ProcessRequest(req *request.Request) *request.Request {
+	if dontMerge, _ := req.Meta[request.MetaDontMergeCookies].(bool); dontMerge {
+		return req
+	}
+
+	jar := m.jars.Jar(req.Meta[request.MetaCookieJar])
+	req.CookieJar = jar
+
+	if m.debug {
+		if u, err := url.Parse(req.URL); err == nil {
+			if sent := jar.Cookies(u); len(sent) > 0 {
+				fmt.Printf("🍪 发送Cookie %s: %v\n", req.URL, sent)
+			}
+		}
+	}
+	return req
+}
+
+// ProcessResponse 统计收到的Cookie；来自HTTP缓存的响应没有经过下载器，在此保存其中的Cookie
+func (m *CookieMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
+	received := (&http.Response{Header: resp.Headers}).Cookies()
+	if len(received) == 0 {
+		return resp
+	}
+
+	m.mutex.Lock()
+	m.stats["cookies/received"] += int64(len(received))
+	m.mutex.Unlock()
+
+	if m.debug {
+		fmt.Printf("🍪 收到Cookie %s: %v\n", resp.URL, received)
+	}
+
+	if cached, _ := resp.Meta[request.MetaCached].(bool); cached && req.CookieJar != nil {
+		if u, err := url.Parse(resp.URL); err == nil {
+			req.CookieJar.SetCookies(u, received)
+		}
+	}
+	return resp
+}
+
+// Close 导出默认Cookie Jar到Cookie文件
+func (m *CookieMiddleware) Close() error {
+	if m.file == "" {
+		return nil
+	}
+	return cookies.SaveFile(m.file, m.jars.Jar(cookies.DefaultJar))
+}
+
+// Stats 返回Cookie统计
+func (m *CookieMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := make(map[string]int64, len(m.stats)+1)
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	stats["cookies/jars"] = int64(len(m.jars.Names()))
+	return stats
+}
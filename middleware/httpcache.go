@@ -0,0 +1,405 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"scrago/request"
+	"scrago/response"
+	"scrago/scheduler"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP缓存策略
+const (
+	CachePolicyDummy   = "dummy"
+	CachePolicyRFC9111 = "rfc9111"
+)
+
+// CachePolicy HTTP缓存策略，决定哪些请求和响应可以缓存、缓存何时需要重新验证
+type CachePolicy interface {
+	// ShouldCacheRequest 请求是否使用缓存
+	ShouldCacheRequest(req *request.Request) bool
+	// ShouldCacheResponse 响应是否保存到缓存
+	ShouldCacheResponse(req *request.Request, resp *response.Response) bool
+	// IsCachedResponseFresh 缓存的响应是否新鲜，新鲜时直接使用，否则发送条件请求重新验证
+	IsCachedResponseFresh(req *request.Request, cached *CachedResponse) bool
+	// IsCachedResponseValid 重新验证时服务器的响应是否表明缓存仍然可用（如304）
+	IsCachedResponseValid(req *request.Request, cached *CachedResponse, resp *response.Response) bool
+}
+
+// NewCachePolicy 按名称创建缓存策略：dummy（默认）或rfc9111
+func NewCachePolicy(name string) (CachePolicy, error) {
+	switch strings.ToLower(name) {
+	case "", CachePolicyDummy:
+		return DummyCachePolicy{}, nil
+	case CachePolicyRFC9111, "rfc2616":
+		return RFC9111CachePolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache policy: %s", name)
+	}
+}
+
+// DummyCachePolicy 缓存所有请求和响应且从不重新验证，适合开发时反复调试解析逻辑
+type DummyCachePolicy struct{}
+
+// ShouldCacheRequest 所有请求都使用缓存
+func (DummyCachePolicy) ShouldCacheRequest(req *request.Request) bool {
+	return true
+}
+
+// ShouldCacheResponse 所有响应都保存
+func (DummyCachePolicy) ShouldCacheResponse(req *request.Request, resp *response.Response) bool {
+	return true
+}
+
+// IsCachedResponseFresh 缓存始终新鲜
+func (DummyCachePolicy) IsCachedResponseFresh(req *request.Request, cached *CachedResponse) bool {
+	return true
+}
+
+// IsCachedResponseValid 缓存始终可用
+func (DummyCachePolicy) IsCachedResponseValid(req *request.Request, cached *CachedResponse, resp *response.Response) bool {
+	return true
+}
+
+// RFC9111CachePolicy 按RFC 9111（HTTP缓存）处理Cache-Control、Expires、ETag和Last-Modified
+//
+// 作为私有缓存：只缓存GET和HEAD请求；遵守请求和响应的no-store、no-cache、max-age，
+// 以及请求的max-stale；没有明确过期时间时按Last-Modified启发式计算（距今时长的10%）。
+// 缓存过期后带上If-None-Match/If-Modified-Since重新验证，304时继续使用缓存，
+// 5xx时若缓存没有must-revalidate也继续使用缓存。
+type RFC9111CachePolicy struct{}
+
+// ShouldCacheRequest 只缓存没有no-store的GET和HEAD请求
+func (RFC9111CachePolicy) ShouldCacheRequest(req *request.Request) bool {
+	method := strings.ToUpper(req.Method)
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	_, noStore := parseCacheControl(req.Headers)["no-store"]
+	return !noStore
+}
+
+// ShouldCacheResponse 有过期时间或验证器的响应才保存
+func (RFC9111CachePolicy) ShouldCacheResponse(req *request.Request, resp *response.Response) bool {
+	cc := parseCacheControl(resp.Headers)
+	if _, noStore := cc["no-store"]; noStore {
+		return false
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	if _, ok := cc["max-age"]; ok || resp.Headers.Get("Expires") != "" {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		// 默认可缓存的永久响应
+		return true
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusUnauthorized:
+		// 没有过期时间时需要至少一个验证器
+		return resp.Headers.Get("Last-Modified") != "" || resp.Headers.Get("ETag") != ""
+	}
+	return false
+}
+
+// IsCachedResponseFresh 比较缓存的当前年龄和新鲜期
+func (RFC9111CachePolicy) IsCachedResponseFresh(req *request.Request, cached *CachedResponse) bool {
+	reqCC := parseCacheControl(req.Headers)
+	if _, noCache := reqCC["no-cache"]; noCache || req.Headers.Get("Pragma") == "no-cache" {
+		return false
+	}
+	if _, noCache := parseCacheControl(cached.Headers)["no-cache"]; noCache {
+		return false
+	}
+
+	lifetime := freshnessLifetime(cached)
+	if maxAge, ok := cacheControlSeconds(reqCC, "max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	if value, ok := reqCC["max-stale"]; ok {
+		if value == "" {
+			// 不限制过期时长
+			return true
+		}
+		if maxStale, ok := cacheControlSeconds(reqCC, "max-stale"); ok {
+			lifetime += maxStale
+		}
+	}
+	return currentAge(cached) < lifetime
+}
+
+// IsCachedResponseValid 304表示缓存仍然有效，5xx时没有must-revalidate的缓存也可以使用
+func (RFC9111CachePolicy) IsCachedResponseValid(req *request.Request, cached *CachedResponse, resp *response.Response) bool {
+	if resp.StatusCode >= 500 {
+		_, mustRevalidate := parseCacheControl(cached.Headers)["must-revalidate"]
+		return !mustRevalidate
+	}
+	return resp.StatusCode == http.StatusNotModified
+}
+
+// freshnessLifetime 缓存的响应的新鲜期：max-age > Expires-Date > Last-Modified启发式 > 永久响应一年
+func freshnessLifetime(cached *CachedResponse) time.Duration {
+	if maxAge, ok := cacheControlSeconds(parseCacheControl(cached.Headers), "max-age"); ok {
+		return maxAge
+	}
+
+	date := responseDate(cached)
+	if expires := cached.Headers.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			// 无效的Expires（如"0"）视为已过期
+			return 0
+		}
+		if t.Before(date) {
+			return 0
+		}
+		return t.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(cached.Headers.Get("Last-Modified")); err == nil && !lastModified.After(date) {
+		return date.Sub(lastModified) / 10
+	}
+
+	switch cached.StatusCode {
+	case http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		return 365 * 24 * time.Hour
+	}
+	return 0
+}
+
+// currentAge 缓存的响应的当前年龄：Age头与传输延迟中较大者，加上在缓存中的时长
+func currentAge(cached *CachedResponse) time.Duration {
+	var age time.Duration
+	if value, err := strconv.Atoi(cached.Headers.Get("Age")); err == nil && value > 0 {
+		age = time.Duration(value) * time.Second
+	}
+	if apparent := cached.StoredAt.Sub(responseDate(cached)); apparent > age {
+		age = apparent
+	}
+	return age + time.Since(cached.StoredAt)
+}
+
+// responseDate 响应的Date头，没有时使用保存时间
+func responseDate(cached *CachedResponse) time.Time {
+	if date, err := http.ParseTime(cached.Headers.Get("Date")); err == nil {
+		return date
+	}
+	return cached.StoredAt
+}
+
+// parseCacheControl 解析Cache-Control指令，指令名转为小写
+func parseCacheControl(headers http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range headers.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return directives
+}
+
+// cacheControlSeconds 读取以秒为单位的指令值
+func cacheControlSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if seconds < 0 {
+		seconds = 0
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// HTTPCacheMiddleware HTTP缓存中间件
+//
+// 在下载前按请求指纹查找缓存：新鲜的缓存直接作为响应返回，不再下载，响应Meta中cached为true；
+// 过期的缓存带上验证器重新请求，服务器确认仍然有效时使用缓存。下载得到的响应按策略保存。
+// 请求Meta中设置 dont_cache 为true时跳过缓存。
+type HTTPCacheMiddleware struct {
+	storage         CacheStorage
+	policy          CachePolicy
+	expire          time.Duration
+	ignoreHTTPCodes map[int]bool
+
+	// 等待重新验证的过期缓存
+	stale sync.Map // *request.Request -> *CachedResponse
+
+	stats map[string]int64
+	mutex sync.Mutex
+}
+
+// NewHTTPCacheMiddleware 创建HTTP缓存中间件
+func NewHTTPCacheMiddleware(storage CacheStorage, policy CachePolicy) *HTTPCacheMiddleware {
+	return &HTTPCacheMiddleware{
+		storage:         storage,
+		policy:          policy,
+		ignoreHTTPCodes: make(map[int]bool),
+		stats:           make(map[string]int64),
+	}
+}
+
+// SetExpire 设置缓存有效期，超过后视为未缓存，0表示永不过期
+func (m *HTTPCacheMiddleware) SetExpire(expire time.Duration) *HTTPCacheMiddleware {
+	m.expire = expire
+	return m
+}
+
+// SetIgnoreHTTPCodes 设置不缓存的响应状态码
+func (m *HTTPCacheMiddleware) SetIgnoreHTTPCodes(codes []int) *HTTPCacheMiddleware {
+	m.ignoreHTTPCodes = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		m.ignoreHTTPCodes[code] = true
+	}
+	return m
+}
+
+// ProcessRequest 缓存在InterceptDownload中处理，此处不修改请求
+func (m *HTTPCacheMiddleware) ProcessRequest(req *request.Request) *request.Request {
+	return req
+}
+
+// InterceptDownload 命中新鲜的缓存时直接返回缓存的响应
+func (m *HTTPCacheMiddleware) InterceptDownload(req *request.Request) *response.Response {
+	if !m.shouldCacheRequest(req) {
+		return nil
+	}
+
+	cached, err := m.storage.Retrieve(scheduler.Fingerprint(req))
+	if err != nil {
+		fmt.Printf("⚠️  读取缓存失败 %s: %v\n", req.URL, err)
+	}
+	if cached == nil || (m.expire > 0 && time.Since(cached.StoredAt) > m.expire) {
+		m.incStats("httpcache/miss")
+		return nil
+	}
+
+	if m.policy.IsCachedResponseFresh(req, cached) {
+		m.incStats("httpcache/hit")
+		return m.cachedResponse(req, cached)
+	}
+
+	// 过期的缓存带上验证器重新请求
+	if etag := cached.Headers.Get("ETag"); etag != "" && req.Headers.Get("If-None-Match") == "" {
+		req.SetHeader("If-None-Match", etag)
+	}
+	if lastModified := cached.Headers.Get("Last-Modified"); lastModified != "" && req.Headers.Get("If-Modified-Since") == "" {
+		req.SetHeader("If-Modified-Since", lastModified)
+	}
+	m.stale.Store(req, cached)
+	return nil
+}
+
+// ProcessResponse 处理重新验证的结果并按策略保存下载的响应
+func (m *HTTPCacheMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
+	if hit, _ := resp.Meta[request.MetaCached].(bool); hit || !m.shouldCacheRequest(req) {
+		return resp
+	}
+
+	key := scheduler.Fingerprint(req)
+	if value, ok := m.stale.LoadAndDelete(req); ok {
+		cached := value.(*CachedResponse)
+		if m.policy.IsCachedResponseValid(req, cached, resp) {
+			if resp.StatusCode == http.StatusNotModified {
+				// 用304的响应头更新缓存
+				m.incStats("httpcache/revalidate")
+				for name, values := range resp.Headers {
+					cached.Headers[name] = values
+				}
+				cached.StoredAt = time.Now()
+				m.store(key, cached)
+			} else {
+				m.incStats("httpcache/errorrecovery")
+			}
+			return m.cachedResponse(req, cached)
+		}
+		m.incStats("httpcache/invalidate")
+	}
+
+	m.incStats("httpcache/firsthand")
+	if m.ignoreHTTPCodes[resp.StatusCode] || !m.policy.ShouldCacheResponse(req, resp) {
+		m.incStats("httpcache/uncacheable")
+		return resp
+	}
+	m.store(key, &CachedResponse{
+		URL:        resp.URL,
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers.Clone(),
+		Body:       resp.Body,
+		StoredAt:   time.Now(),
+	})
+	return resp
+}
+
+// ProcessException 下载失败时丢弃等待重新验证的缓存
+func (m *HTTPCacheMiddleware) ProcessException(req *request.Request, err error) *request.Request {
+	m.DownloadFinished(req)
+	return nil
+}
+
+// DownloadFinished 重新验证的响应没有经过ProcessResponse时（如被重试或请求失败）丢弃等待重新验证的缓存，
+// 重试的请求会重新读取缓存
+func (m *HTTPCacheMiddleware) DownloadFinished(req *request.Request) {
+	if _, ok := m.stale.LoadAndDelete(req); ok {
+		m.incStats("httpcache/revalidate_aborted")
+	}
+}
+
+// Close 关闭缓存存储
+func (m *HTTPCacheMiddleware) Close() error {
+	return m.storage.Close()
+}
+
+// Stats 返回缓存命中、未命中、保存等统计
+func (m *HTTPCacheMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := make(map[string]int64, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	return stats
+}
+
+// shouldCacheRequest 请求是否使用缓存
+func (m *HTTPCacheMiddleware) shouldCacheRequest(req *request.Request) bool {
+	if dontCache, _ := req.Meta[request.MetaDontCache].(bool); dontCache {
+		return false
+	}
+	return m.policy.ShouldCacheRequest(req)
+}
+
+// cachedResponse 由缓存创建响应并在Meta中标记
+func (m *HTTPCacheMiddleware) cachedResponse(req *request.Request, cached *CachedResponse) *response.Response {
+	resp := response.NewResponse(cached.URL, cached.StatusCode, cached.Headers, cached.Body, req)
+	resp.SetMeta(request.MetaCached, true)
+	return resp
+}
+
+// store 保存响应，失败时只打印警告
+func (m *HTTPCacheMiddleware) store(key string, cached *CachedResponse) {
+	if err := m.storage.Store(key, cached); err != nil {
+		fmt.Printf("⚠️  保存缓存失败 %s: %v\n", cached.URL, err)
+		return
+	}
+	m.incStats("httpcache/store")
+}
+
+func (m *HTTPCacheMiddleware) incStats(key string) {
+	m.mutex.Lock()
+	m.stats[key]++
+	m.mutex.Unlock()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP缓存存储后端
+const (
+	CacheStorageFilesystem = "filesystem"
+	CacheStorageMemory     = "memory"
+)
+
+// CachedResponse 缓存的响应
+type CachedResponse struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Headers    http.Header `json:"headers"`
+	Body       []byte      `json:"-"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// CacheStorage HTTP缓存存储，key为请求指纹
+type CacheStorage interface {
+	// Retrieve 读取缓存的响应，不存在时返回nil, nil
+	Retrieve(key string) (*CachedResponse, error)
+	Store(key string, resp *CachedResponse) error
+	Close() error
+}
+
+// NewCacheStorage 按名称创建缓存存储：filesystem（默认，保存到dir）或memory
+func NewCacheStorage(backend, dir string) (CacheStorage, error) {
+	switch strings.ToLower(backend) {
+	case "", CacheStorageFilesystem:
+		return NewFilesystemCacheStorage(dir)
+	case CacheStorageMemory:
+		return NewMemoryCacheStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown cache storage: %s", backend)
+	}
+}
+
+// FilesystemCacheStorage 文件系统缓存存储
+//
+// 每个响应保存在 <dir>/<key前两位>/<key>/ 下：meta.json 保存URL、状态码、响应头和保存时间，
+// body 保存响应体。先写body再写meta.json，均先写临时文件再重命名，中断时不会留下不完整的缓存。
+type FilesystemCacheStorage struct {
+	dir string
+}
+
+// NewFilesystemCacheStorage 创建文件系统缓存存储
+func NewFilesystemCacheStorage(dir string) (*FilesystemCacheStorage, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache dir is empty")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	return &FilesystemCacheStorage{dir: dir}, nil
+}
+
+// path 缓存条目所在目录
+func (s *FilesystemCacheStorage) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(s.dir, key)
+	}
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+// Retrieve 读取缓存的响应
+func (s *FilesystemCacheStorage) Retrieve(key string) (*CachedResponse, error) {
+	dir := s.path(key)
+
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("parse cache meta %s: %w", key, err)
+	}
+	if cached.Body, err = os.ReadFile(filepath.Join(dir, "body")); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// Store 保存响应
+func (s *FilesystemCacheStorage) Store(key string, resp *CachedResponse) error {
+	dir := s.path(key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "body"), resp.Body); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "meta.json"), meta)
+}
+
+// Close 文件系统存储无需关闭
+func (s *FilesystemCacheStorage) Close() error {
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再重命名
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// MemoryCacheStorage 内存缓存存储，进程退出后失效
+type MemoryCacheStorage struct {
+	responses map[string]*CachedResponse
+	mutex     sync.RWMutex
+}
+
+// NewMemoryCacheStorage 创建内存缓存存储
+func NewMemoryCacheStorage() *MemoryCacheStorage {
+	return &MemoryCacheStorage{responses: make(map[string]*CachedResponse)}
+}
+
+// Retrieve 读取缓存的响应（副本）
+func (s *MemoryCacheStorage) Retrieve(key string) (*CachedResponse, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cached, exists := s.responses[key]
+	if !exists {
+		return nil, nil
+	}
+	return cached.clone(), nil
+}
+
+// Store 保存响应（副本）
+func (s *MemoryCacheStorage) Store(key string, resp *CachedResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.responses[key] = resp.clone()
+	return nil
+}
+
+// Close 清空缓存
+func (s *MemoryCacheStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.responses = make(map[string]*CachedResponse)
+	return nil
+}
+
+// clone 复制缓存的响应，避免调用方修改存储中的数据
+func (c *CachedResponse) clone() *CachedResponse {
+	cp := *c
+	cp.Headers = c.Headers.Clone()
+	cp.Body = append([]byte(nil), c.Body...)
+	return &cp
+}
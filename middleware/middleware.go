@@ -1,11 +1,9 @@
 package middleware
 
 import (
-	"fmt"
 	"scrago/request"
 	"scrago/response"
 	"math/rand"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +15,33 @@ type Middleware interface {
 	ProcessResponse(req *request.Request, resp *response.Response) *response.Response
 }
 
+// ExceptionMiddleware 异常处理中间件（可选接口）
+// 下载失败时调用，返回非nil请求表示由引擎重新调度该请求
+type ExceptionMiddleware interface {
+	ProcessException(req *request.Request, err error) *request.Request
+}
+
+// RescheduleMiddleware 重新调度中间件（可选接口）
+// 在该中间件的ProcessResponse之前调用，返回非nil请求表示丢弃当前响应并重新调度该请求；
+// 返回非nil错误表示请求失败，响应不再交给回调，由引擎计入失败并调用错误回调
+type RescheduleMiddleware interface {
+	RescheduleResponse(req *request.Request, resp *response.Response) (*request.Request, error)
+}
+
+// DownloadInterceptor 下载拦截中间件（可选接口）
+// 在所有中间件的ProcessRequest之后、下载之前调用，返回非nil响应表示跳过下载直接使用该响应（如HTTP缓存），
+// 该响应同样经过中间件的ProcessResponse
+type DownloadInterceptor interface {
+	InterceptDownload(req *request.Request) *response.Response
+}
+
+// DownloadFinisher 下载结束通知（可选接口）
+// 请求处理结束时调用，无论响应是否经过该中间件（如被重试中间件重新调度、请求失败或被丢弃），
+// 用于清理InterceptDownload中为请求保存的状态
+type DownloadFinisher interface {
+	DownloadFinished(req *request.Request)
+}
+
 // UserAgentMiddleware User-Agent中间件
 type UserAgentMiddleware struct {
 	userAgents []string
@@ -91,116 +116,6 @@ func (m *ProxyMiddleware) ProcessResponse(req *request.Request, resp *response.R
 	return resp
 }
 
-// RetryMiddleware 重试中间件
-type RetryMiddleware struct {
-	maxRetries     int
-	retryHTTPCodes []int
-}
-
-// NewRetryMiddleware 创建重试中间件
-func NewRetryMiddleware(maxRetries int, retryHTTPCodes []int) *RetryMiddleware {
-	if len(retryHTTPCodes) == 0 {
-		retryHTTPCodes = []int{500, 502, 503, 504, 408, 429}
-	}
-	
-	return &RetryMiddleware{
-		maxRetries:     maxRetries,
-		retryHTTPCodes: retryHTTPCodes,
-	}
-}
-
-// ProcessRequest 处理请求
-func (m *RetryMiddleware) ProcessRequest(req *request.Request) *request.Request {
-	return req
-}
-
-// ProcessResponse 处理响应
-func (m *RetryMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
-	// 检查是否需要重试
-	if m.shouldRetry(resp.StatusCode) && req.RetryTimes < m.maxRetries {
-		req.RetryTimes++
-		// 这里应该重新调度请求，但由于架构限制，我们只是标记
-		fmt.Printf("Retrying request %s (attempt %d/%d)\n", req.URL, req.RetryTimes, m.maxRetries)
-	}
-	
-	return resp
-}
-
-// shouldRetry 检查是否应该重试
-func (m *RetryMiddleware) shouldRetry(statusCode int) bool {
-	for _, code := range m.retryHTTPCodes {
-		if statusCode == code {
-			return true
-		}
-	}
-	return false
-}
-
-// CookieMiddleware Cookie中间件
-type CookieMiddleware struct {
-	cookieJar map[string][]*http.Cookie
-}
-
-// NewCookieMiddleware 创建Cookie中间件
-func NewCookieMiddleware() *CookieMiddleware {
-	return &CookieMiddleware{
-		cookieJar: make(map[string][]*http.Cookie),
-	}
-}
-
-// ProcessRequest 处理请求
-func (m *CookieMiddleware) ProcessRequest(req *request.Request) *request.Request {
-	// 从cookie jar中获取cookies
-	if cookies, exists := m.cookieJar[m.getDomain(req.URL)]; exists {
-		for _, cookie := range cookies {
-			req.AddCookie(cookie)
-		}
-	}
-	return req
-}
-
-// ProcessResponse 处理响应
-func (m *CookieMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
-	// 保存响应中的cookies
-	if setCookies := resp.Headers["Set-Cookie"]; len(setCookies) > 0 {
-		domain := m.getDomain(req.URL)
-		for _, setCookie := range setCookies {
-			if cookie := m.parseCookie(setCookie); cookie != nil {
-				m.cookieJar[domain] = append(m.cookieJar[domain], cookie)
-			}
-		}
-	}
-	return resp
-}
-
-// getDomain 获取域名
-func (m *CookieMiddleware) getDomain(url string) string {
-	parts := strings.Split(url, "/")
-	if len(parts) >= 3 {
-		return parts[2]
-	}
-	return url
-}
-
-// parseCookie 解析Cookie
-func (m *CookieMiddleware) parseCookie(setCookie string) *http.Cookie {
-	// 简单的Cookie解析，实际应该使用更完善的解析器
-	parts := strings.Split(setCookie, ";")
-	if len(parts) == 0 {
-		return nil
-	}
-	
-	nameValue := strings.Split(strings.TrimSpace(parts[0]), "=")
-	if len(nameValue) != 2 {
-		return nil
-	}
-	
-	return &http.Cookie{
-		Name:  nameValue[0],
-		Value: nameValue[1],
-	}
-}
-
 // DelayMiddleware 延迟中间件
 type DelayMiddleware struct {
 	delay      time.Duration
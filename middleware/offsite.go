@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"scrago/request"
+	"scrago/response"
+	"scrago/spider"
+	"strings"
+	"sync"
+)
+
+// OffsiteMiddleware 站外请求过滤中间件
+//
+// 丢弃主机不在爬虫允许域名（含子域名）内的请求，爬虫没有设置允许域名时不过滤。
+// 请求设置了DontFilter或Meta["allow_offsite"]为true时不过滤。
+// 每个被过滤的域名只在第一次出现时打印一次。引擎总会在爬虫中间件之后执行站外过滤。
+type OffsiteMiddleware struct {
+	BaseSpiderMiddleware
+	filtered int64
+	domains  map[string]bool // 已被过滤过的域名
+	mutex    sync.Mutex
+}
+
+// NewOffsiteMiddleware 创建站外请求过滤中间件
+func NewOffsiteMiddleware() *OffsiteMiddleware {
+	return &OffsiteMiddleware{
+		domains: make(map[string]bool),
+	}
+}
+
+// ProcessSpiderOutput 过滤站外请求
+func (m *OffsiteMiddleware) ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{} {
+	provider, ok := s.(spider.AllowedDomainsProvider)
+	if !ok {
+		return results
+	}
+	allowed := normalizeDomains(provider.AllowedDomains())
+	if len(allowed) == 0 {
+		return results
+	}
+
+	return filterRequests(results, func(req *request.Request) bool {
+		if req.DontFilter {
+			return true
+		}
+		if allow, _ := req.Meta[request.MetaAllowOffsite].(bool); allow {
+			return true
+		}
+
+		host, ok := allowedHost(req.URL, allowed)
+		if ok {
+			return true
+		}
+
+		m.mutex.Lock()
+		m.filtered++
+		first := !m.domains[host]
+		m.domains[host] = true
+		m.mutex.Unlock()
+
+		if first {
+			fmt.Printf("🚫 过滤站外请求，域名 %s 不在允许范围内: %s\n", host, req.URL)
+		}
+		return false
+	})
+}
+
+// Stats 统计信息
+func (m *OffsiteMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return map[string]int64{
+		"offsite/filtered": m.filtered,
+		"offsite/domains":  int64(len(m.domains)),
+	}
+}
+
+// normalizeDomains 规范化允许的域名，误写成URL（如 https://example.com/）时取其主机名
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if strings.Contains(domain, "://") {
+			if parsedURL, err := url.Parse(domain); err == nil {
+				domain = parsedURL.Hostname()
+			}
+		}
+		domain = strings.Trim(domain, "./")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// allowedHost 判断URL的主机是否为允许的域名或其子域名，返回主机名
+func allowedHost(rawURL string, domains []string) (string, bool) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, false
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return host, true
+		}
+	}
+	return host, false
+}
@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"scrago/request"
+	"scrago/response"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryDelayMetaKey 重试请求的延迟（time.Duration），引擎据此延迟重新入队
+const RetryDelayMetaKey = "retry_delay"
+
+// RetryBackoff 指数退避配置
+type RetryBackoff struct {
+	Base       time.Duration // 首次重试的延迟
+	Max        time.Duration // 延迟上限
+	Multiplier float64       // 每次重试的延迟倍数
+	Jitter     float64       // 随机抖动比例（0~1）
+}
+
+// DefaultRetryBackoff 默认退避配置
+func DefaultRetryBackoff() RetryBackoff {
+	return RetryBackoff{
+		Base:       500 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Delay 计算第attempt次重试（从1开始）的延迟
+func (b RetryBackoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Base) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	// 抖动范围 [1-jitter, 1+jitter]
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		delay *= 1 + jitter*(rand.Float64()*2-1)
+	}
+
+	return time.Duration(delay)
+}
+
+// HTTPStatusError 需要重试的HTTP状态码，Response为收到的响应
+type HTTPStatusError struct {
+	Response *response.Response
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.Response.StatusCode)
+}
+
+// RetryExhaustedError 重试次数耗尽，Err为最后一次的错误
+type RetryExhaustedError struct {
+	Reason     string
+	RetryTimes int
+	Err        error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("gave up retrying after %d retries (%s): %v", e.RetryTimes, e.Reason, e.Err)
+}
+
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// RetryMiddleware 重试中间件
+type RetryMiddleware struct {
+	maxRetries     int
+	retryHTTPCodes []int
+	backoff        RetryBackoff
+
+	stats map[string]int64
+	mutex sync.Mutex
+}
+
+// NewRetryMiddleware 创建重试中间件
+func NewRetryMiddleware(maxRetries int, retryHTTPCodes []int) *RetryMiddleware {
+	if len(retryHTTPCodes) == 0 {
+		retryHTTPCodes = []int{500, 502, 503, 504, 408, 429}
+	}
+
+	return &RetryMiddleware{
+		maxRetries:     maxRetries,
+		retryHTTPCodes: retryHTTPCodes,
+		backoff:        DefaultRetryBackoff(),
+		stats:          make(map[string]int64),
+	}
+}
+
+// SetBackoff 设置退避配置
+func (m *RetryMiddleware) SetBackoff(backoff RetryBackoff) *RetryMiddleware {
+	m.backoff = backoff
+	return m
+}
+
+// ProcessRequest 处理请求
+func (m *RetryMiddleware) ProcessRequest(req *request.Request) *request.Request {
+	return req
+}
+
+// ProcessResponse 处理响应
+func (m *RetryMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
+	return resp
+}
+
+// RescheduleResponse 状态码需要重试时返回重试请求，重试次数耗尽时返回RetryExhaustedError，
+// 由引擎将请求计为失败并调用错误回调，而不是把错误响应交给回调
+func (m *RetryMiddleware) RescheduleResponse(req *request.Request, resp *response.Response) (*request.Request, error) {
+	if req.DontRetry || !m.shouldRetry(resp.StatusCode) {
+		return nil, nil
+	}
+
+	reason := fmt.Sprintf("status_%d", resp.StatusCode)
+	statusErr := &HTTPStatusError{Response: resp}
+	if retryReq := m.retry(req, reason, statusErr); retryReq != nil {
+		return retryReq, nil
+	}
+	return nil, &RetryExhaustedError{Reason: reason, RetryTimes: req.RetryTimes, Err: statusErr}
+}
+
+// ProcessException 网络错误或超时时返回重试请求，次数耗尽时返回nil，由引擎以该错误将请求计为失败
+func (m *RetryMiddleware) ProcessException(req *request.Request, err error) *request.Request {
+	reason := classifyRetryError(err)
+	if reason == "" || req.DontRetry {
+		return nil
+	}
+
+	return m.retry(req, reason, err)
+}
+
+// retry 生成重试请求，次数耗尽时返回nil
+func (m *RetryMiddleware) retry(req *request.Request, reason string, err error) *request.Request {
+	if req.RetryTimes >= m.maxRetries {
+		m.mutex.Lock()
+		m.stats["retry/max_reached"]++
+		m.mutex.Unlock()
+
+		fmt.Printf("❌ 重试次数耗尽，放弃请求 %s (已重试 %d 次): %v\n", req.URL, req.RetryTimes, err)
+		return nil
+	}
+
+	retryReq := req.Copy()
+	retryReq.RetryTimes++
+	// 重试请求的指纹已被记录，需跳过去重
+	retryReq.DontFilter = true
+	delay := m.backoff.Delay(retryReq.RetryTimes)
+	retryReq.SetMeta(RetryDelayMetaKey, delay)
+
+	m.mutex.Lock()
+	m.stats["retry/count"]++
+	m.stats["retry/reason_count/"+reason]++
+	m.mutex.Unlock()
+
+	fmt.Printf("🔁 Retrying request %s (attempt %d/%d, reason: %s, delay: %v)\n",
+		req.URL, retryReq.RetryTimes, m.maxRetries, reason, delay.Round(time.Millisecond))
+
+	return retryReq
+}
+
+// shouldRetry 检查是否应该重试
+func (m *RetryMiddleware) shouldRetry(statusCode int) bool {
+	for _, code := range m.retryHTTPCodes {
+		if statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats 返回按原因统计的重试次数
+func (m *RetryMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := make(map[string]int64, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	return stats
+}
+
+// classifyRetryError 判断下载错误是否可重试，返回重试原因，不可重试时返回空串
+func classifyRetryError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var tlsErr tls.RecordHeaderError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsNotFound {
+			return ""
+		}
+		return "dns_error"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection_reset"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_closed"
+	case errors.As(err, &tlsErr):
+		return "tls_error"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network_error"
+	}
+
+	// http.Client的超时错误不一定实现net.Error
+	if strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		return "timeout"
+	}
+
+	return ""
+}
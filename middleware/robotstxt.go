@@ -0,0 +1,442 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"scrago/downloader"
+	"scrago/request"
+	"scrago/response"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robots.txt获取失败（网络错误或5xx）时的处理策略
+const (
+	RobotsAllowOnFailure = "allow"
+	RobotsDenyOnFailure  = "deny"
+)
+
+// maxRobotsSize robots.txt最多读取的字节数
+const maxRobotsSize = 512 * 1024
+
+// RobotsTxtMiddleware robots.txt中间件
+//
+// 按源（scheme://host:port）获取并缓存robots.txt，丢弃被禁止的请求。
+// Crawl-delay和Request-rate会作为对应下载槽的延迟下限。
+// robots.txt返回4xx时视为没有限制，网络错误或5xx时按失败策略允许或全部禁止。
+// 请求Meta中设置 dont_obey_robotstxt 为true时跳过检查。
+type RobotsTxtMiddleware struct {
+	userAgent     string
+	productToken  string
+	failurePolicy string
+	client        *http.Client
+	slots         *downloader.SlotManager
+
+	cache map[string]*robotsEntry
+	stats map[string]int64
+	mutex sync.Mutex
+}
+
+// robotsEntry 缓存的robots.txt，ready关闭后rules可用
+type robotsEntry struct {
+	ready chan struct{}
+	rules *RobotsRules
+}
+
+// NewRobotsTxtMiddleware 创建robots.txt中间件，userAgent用于获取robots.txt及匹配规则组
+func NewRobotsTxtMiddleware(userAgent string) *RobotsTxtMiddleware {
+	return &RobotsTxtMiddleware{
+		userAgent:     userAgent,
+		productToken:  productToken(userAgent),
+		failurePolicy: RobotsAllowOnFailure,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		cache:         make(map[string]*robotsEntry),
+		stats:         make(map[string]int64),
+	}
+}
+
+// SetFailurePolicy 设置robots.txt获取失败时的策略（allow或deny）
+func (m *RobotsTxtMiddleware) SetFailurePolicy(policy string) *RobotsTxtMiddleware {
+	m.failurePolicy = policy
+	return m
+}
+
+// SetDownloadSlots 设置下载槽管理器，用于应用Crawl-delay和Request-rate
+func (m *RobotsTxtMiddleware) SetDownloadSlots(slots *downloader.SlotManager) *RobotsTxtMiddleware {
+	m.slots = slots
+	return m
+}
+
+// ProcessRequest 处理请求，被robots.txt禁止的请求返回nil
+func (m *RobotsTxtMiddleware) ProcessRequest(req *request.Request) *request.Request {
+	if dont, _ := req.Meta[request.MetaDontObeyRobotsTxt].(bool); dont {
+		return req
+	}
+
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return req
+	}
+	if parsedURL.Path == "/robots.txt" {
+		return req
+	}
+
+	rules := m.rules(parsedURL, req)
+	if rules.Allowed(m.productToken, parsedURL.RequestURI()) {
+		return req
+	}
+
+	m.incStat("robotstxt/forbidden")
+	fmt.Printf("🤖 robots.txt禁止访问: %s\n", req.URL)
+	return nil
+}
+
+// ProcessResponse 处理响应
+func (m *RobotsTxtMiddleware) ProcessResponse(req *request.Request, resp *response.Response) *response.Response {
+	return resp
+}
+
+// Sitemaps 返回URL所在站点robots.txt中声明的Sitemap
+func (m *RobotsTxtMiddleware) Sitemaps(rawURL string) ([]string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return m.rules(parsedURL, nil).Sitemaps, nil
+}
+
+// Rules 返回URL所在站点的robots.txt规则
+func (m *RobotsTxtMiddleware) Rules(rawURL string) (*RobotsRules, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return m.rules(parsedURL, nil), nil
+}
+
+// rules 获取源对应的规则，同一个源只获取一次，并发请求等待同一次获取
+func (m *RobotsTxtMiddleware) rules(u *url.URL, req *request.Request) *RobotsRules {
+	origin := u.Scheme + "://" + u.Host
+
+	m.mutex.Lock()
+	entry, exists := m.cache[origin]
+	if !exists {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		m.cache[origin] = entry
+	}
+	m.mutex.Unlock()
+
+	if exists {
+		<-entry.ready
+		return entry.rules
+	}
+
+	entry.rules = m.fetch(origin)
+	close(entry.ready)
+
+	// Crawl-delay / Request-rate 作为下载槽的延迟下限
+	if m.slots != nil && req != nil {
+		if delay := entry.rules.CrawlDelay(m.productToken); delay > 0 {
+			m.slots.SetMinDelay(m.slots.SlotKey(req), delay)
+		}
+	}
+
+	return entry.rules
+}
+
+// fetch 下载并解析robots.txt
+func (m *RobotsTxtMiddleware) fetch(origin string) *RobotsRules {
+	m.incStat("robotstxt/request_count")
+
+	httpReq, err := http.NewRequest("GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return m.failure(origin, err)
+	}
+	httpReq.Header.Set("User-Agent", m.userAgent)
+
+	httpResp, err := m.client.Do(httpReq)
+	if err != nil {
+		return m.failure(origin, err)
+	}
+	defer httpResp.Body.Close()
+
+	m.incStat("robotstxt/response_count")
+	m.incStat(fmt.Sprintf("robotstxt/response_status_count/%d", httpResp.StatusCode))
+
+	switch {
+	case httpResp.StatusCode >= 500:
+		return m.failure(origin, fmt.Errorf("status %d", httpResp.StatusCode))
+	case httpResp.StatusCode >= 400:
+		// robots.txt不存在或无权访问，视为没有限制
+		return &RobotsRules{}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxRobotsSize))
+	if err != nil {
+		return m.failure(origin, err)
+	}
+	return ParseRobotsTxt(body)
+}
+
+// failure 按失败策略生成规则
+func (m *RobotsTxtMiddleware) failure(origin string, err error) *RobotsRules {
+	m.incStat("robotstxt/fetch_error")
+	fmt.Printf("⚠️  获取robots.txt失败 (%s，策略: %s): %v\n", origin, m.failurePolicy, err)
+
+	if m.failurePolicy == RobotsDenyOnFailure {
+		return &RobotsRules{disallowAll: true}
+	}
+	return &RobotsRules{}
+}
+
+// incStat 增加统计计数
+func (m *RobotsTxtMiddleware) incStat(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats[key]++
+}
+
+// Stats 统计信息
+func (m *RobotsTxtMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := make(map[string]int64, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	return stats
+}
+
+// productToken 从User-Agent中取出产品名，如 "go-scrapy/1.0 (+https://...)" 得到 "go-scrapy"
+func productToken(userAgent string) string {
+	token := userAgent
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(token))
+}
+
+// RobotsRules 解析后的robots.txt
+type RobotsRules struct {
+	groups      []*robotsGroup
+	Sitemaps    []string
+	disallowAll bool
+}
+
+// robotsGroup 一组User-agent共享的规则
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRule 单条Allow/Disallow规则
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobotsTxt 解析robots.txt内容
+func ParseRobotsTxt(data []byte) *RobotsRules {
+	rules := &RobotsRules{}
+
+	var group *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 连续的User-agent行属于同一组
+			if !inAgents {
+				group = &robotsGroup{}
+				rules.groups = append(rules.groups, group)
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			rules.Sitemaps = append(rules.Sitemaps, value)
+		case "allow", "disallow":
+			if group != nil && value != "" {
+				group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if group != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "request-rate":
+			// 格式为 请求数/秒数，如 1/5 表示每5秒一个请求
+			if group != nil {
+				if delay := parseRequestRate(value); delay > group.crawlDelay {
+					group.crawlDelay = delay
+				}
+			}
+		}
+		inAgents = false
+	}
+
+	return rules
+}
+
+// parseRequestRate 将Request-rate转换为请求间隔，支持 1/5、1/5s、1/10m、1/1h
+func parseRequestRate(value string) time.Duration {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	requests, period, found := strings.Cut(fields[0], "/")
+	if !found {
+		return 0
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return 0
+	}
+
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(period, "s"):
+		period = strings.TrimSuffix(period, "s")
+	case strings.HasSuffix(period, "m"):
+		period, unit = strings.TrimSuffix(period, "m"), time.Minute
+	case strings.HasSuffix(period, "h"):
+		period, unit = strings.TrimSuffix(period, "h"), time.Hour
+	}
+
+	seconds, err := strconv.ParseFloat(period, 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(unit) / float64(n))
+}
+
+// group 选择与产品名匹配（不区分大小写）的规则组，没有时使用 * 组；同名的多个组合并
+func (r *RobotsRules) group(token string) *robotsGroup {
+	var matched, wildcard *robotsGroup
+
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			switch agent {
+			case token:
+				matched = mergeGroup(matched, g)
+			case "*":
+				wildcard = mergeGroup(wildcard, g)
+			default:
+				continue
+			}
+			break
+		}
+	}
+
+	if matched != nil {
+		return matched
+	}
+	return wildcard
+}
+
+// mergeGroup 合并规则组
+func mergeGroup(dst, src *robotsGroup) *robotsGroup {
+	if dst == nil {
+		return src
+	}
+
+	merged := &robotsGroup{
+		agents:     dst.agents,
+		rules:      append(append([]robotsRule{}, dst.rules...), src.rules...),
+		crawlDelay: dst.crawlDelay,
+	}
+	if src.crawlDelay > merged.crawlDelay {
+		merged.crawlDelay = src.crawlDelay
+	}
+	return merged
+}
+
+// Allowed 判断User-Agent是否可以访问路径（含查询参数）
+// 匹配最长的规则生效，长度相同时Allow优先
+func (r *RobotsRules) Allowed(token, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+
+	g := r.group(strings.ToLower(token))
+	if g == nil {
+		return true
+	}
+
+	allowed := true
+	matchedLen := -1
+	for _, rule := range g.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > matchedLen || (len(rule.pattern) == matchedLen && rule.allow) {
+			allowed = rule.allow
+			matchedLen = len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay 返回User-Agent对应的Crawl-delay（或由Request-rate换算的间隔）
+func (r *RobotsRules) CrawlDelay(token string) time.Duration {
+	if g := r.group(strings.ToLower(token)); g != nil {
+		return g.crawlDelay
+	}
+	return 0
+}
+
+// matchRobotsPattern 匹配robots.txt路径模式，支持 * 通配符和结尾的 $
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		// 锚定时最后一段必须出现在路径末尾
+		if anchored && i == len(parts)-1 {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
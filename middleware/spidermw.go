@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"scrago/request"
+	"scrago/response"
+	"scrago/spider"
+	"strings"
+	"sync"
+)
+
+// SpiderMiddleware 爬虫中间件接口，位于引擎与爬虫回调之间
+//
+// 按添加顺序依次调用。resp为nil表示结果来自下载失败后的错误回调。
+type SpiderMiddleware interface {
+	// ProcessSpiderInput 响应交给回调解析前调用，返回错误时不再解析，转交ProcessSpiderException
+	ProcessSpiderInput(resp *response.Response, s spider.Spider) error
+	// ProcessSpiderOutput 处理回调返回的请求和数据项，返回过滤或修改后的结果
+	ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{}
+	// ProcessSpiderException 回调panic、返回error或输入检查失败时调用，
+	// 返回非nil结果表示异常已处理（结果继续经过ProcessSpiderOutput），后续中间件不再调用
+	ProcessSpiderException(resp *response.Response, err error, s spider.Spider) []interface{}
+	// ProcessStartRequests 处理爬虫的起始请求
+	ProcessStartRequests(reqs []*request.Request, s spider.Spider) []*request.Request
+}
+
+// BaseSpiderMiddleware 爬虫中间件的空实现，嵌入后只需实现关心的方法
+type BaseSpiderMiddleware struct{}
+
+// ProcessSpiderInput 处理输入
+func (m *BaseSpiderMiddleware) ProcessSpiderInput(resp *response.Response, s spider.Spider) error {
+	return nil
+}
+
+// ProcessSpiderOutput 处理输出
+func (m *BaseSpiderMiddleware) ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{} {
+	return results
+}
+
+// ProcessSpiderException 处理异常
+func (m *BaseSpiderMiddleware) ProcessSpiderException(resp *response.Response, err error, s spider.Spider) []interface{} {
+	return nil
+}
+
+// ProcessStartRequests 处理起始请求
+func (m *BaseSpiderMiddleware) ProcessStartRequests(reqs []*request.Request, s spider.Spider) []*request.Request {
+	return reqs
+}
+
+// filterRequests 按条件过滤结果中的请求，数据项原样保留
+func filterRequests(results []interface{}, keep func(req *request.Request) bool) []interface{} {
+	filtered := results[:0]
+	for _, result := range results {
+		if req, ok := result.(*request.Request); ok && !keep(req) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// DepthMiddleware 深度中间件
+//
+// 丢弃深度超过maxDepth的请求（0表示不限制），并按深度调整请求优先级：
+// priority为正时越深的请求优先级越低（广度优先），为负时越深越优先（深度优先）。
+// 优先级只在使用优先级调度器时生效。同时按深度统计请求数。
+type DepthMiddleware struct {
+	BaseSpiderMiddleware
+	maxDepth int
+	priority int
+	filtered int64
+	counts   map[int]int64 // 各深度的请求数
+	mutex    sync.Mutex
+}
+
+// NewDepthMiddleware 创建深度中间件
+func NewDepthMiddleware(maxDepth int) *DepthMiddleware {
+	return &DepthMiddleware{
+		maxDepth: maxDepth,
+		counts:   make(map[int]int64),
+	}
+}
+
+// SetPriority 设置每层深度调整的优先级（DEPTH_PRIORITY）
+func (m *DepthMiddleware) SetPriority(priority int) *DepthMiddleware {
+	m.priority = priority
+	return m
+}
+
+// ProcessStartRequests 统计深度为0的起始请求
+func (m *DepthMiddleware) ProcessStartRequests(reqs []*request.Request, s spider.Spider) []*request.Request {
+	m.mutex.Lock()
+	m.counts[0] += int64(len(reqs))
+	m.mutex.Unlock()
+	return reqs
+}
+
+// ProcessSpiderOutput 丢弃过深的请求并调整优先级，请求深度由引擎在回调返回后设置
+func (m *DepthMiddleware) ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{} {
+	return filterRequests(results, func(req *request.Request) bool {
+		depth := req.Depth()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		if m.maxDepth > 0 && depth > m.maxDepth {
+			m.filtered++
+			return false
+		}
+		req.Priority -= depth * m.priority
+		m.counts[depth]++
+		return true
+	})
+}
+
+// Stats 统计信息
+func (m *DepthMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := map[string]int64{"depth/filtered": m.filtered}
+	maxDepth := 0
+	for depth, count := range m.counts {
+		stats[fmt.Sprintf("depth/request_count/%d", depth)] = count
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	stats["depth/max"] = int64(maxDepth)
+	return stats
+}
+
+// URLLengthMiddleware URL长度限制中间件，丢弃URL过长的请求
+type URLLengthMiddleware struct {
+	BaseSpiderMiddleware
+	maxLength int
+	ignored   int64
+	mutex     sync.Mutex
+}
+
+// NewURLLengthMiddleware 创建URL长度限制中间件，maxLength为0时使用2083
+func NewURLLengthMiddleware(maxLength int) *URLLengthMiddleware {
+	if maxLength <= 0 {
+		maxLength = 2083
+	}
+	return &URLLengthMiddleware{maxLength: maxLength}
+}
+
+// ProcessSpiderOutput 丢弃URL过长的请求
+func (m *URLLengthMiddleware) ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{} {
+	return filterRequests(results, func(req *request.Request) bool {
+		if len(req.URL) <= m.maxLength {
+			return true
+		}
+		fmt.Printf("⚠️  忽略URL过长的请求 (%d > %d): %.100s...\n", len(req.URL), m.maxLength, req.URL)
+		m.mutex.Lock()
+		m.ignored++
+		m.mutex.Unlock()
+		return false
+	})
+}
+
+// Stats 统计信息
+func (m *URLLengthMiddleware) Stats() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return map[string]int64{"urllength/request_ignored_count": m.ignored}
+}
+
+// RefererMiddleware Referer中间件，为由响应产生的请求设置Referer请求头
+// 与浏览器默认策略一致：从HTTPS页面发往HTTP的请求不带Referer
+type RefererMiddleware struct {
+	BaseSpiderMiddleware
+}
+
+// NewRefererMiddleware 创建Referer中间件
+func NewRefererMiddleware() *RefererMiddleware {
+	return &RefererMiddleware{}
+}
+
+// ProcessSpiderOutput 设置Referer
+func (m *RefererMiddleware) ProcessSpiderOutput(resp *response.Response, results []interface{}, s spider.Spider) []interface{} {
+	if resp == nil {
+		return results
+	}
+
+	referer, err := url.Parse(resp.URL)
+	if err != nil {
+		return results
+	}
+	// Referer中不包含片段和用户信息
+	referer.Fragment = ""
+	referer.User = nil
+
+	for _, result := range results {
+		req, ok := result.(*request.Request)
+		if !ok || req.Headers.Get("Referer") != "" {
+			continue
+		}
+		if referer.Scheme == "https" && strings.HasPrefix(strings.ToLower(req.URL), "http:") {
+			continue
+		}
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
+		req.SetHeader("Referer", referer.String())
+	}
+	return results
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"fmt"
+	"scrago/settings"
+	"sort"
+	"strings"
+)
+
+// FeedExportPipeline 按FEEDS_EXPORT配置将数据项同时写入多个输出文件
+type FeedExportPipeline struct {
+	feeds []Pipeline
+}
+
+// NewFeedExportPipeline 创建输出管道，按格式（json、csv、xml）为每个输出创建对应的文件管道
+func NewFeedExportPipeline(feeds map[string]settings.FeedExportSettings) (*FeedExportPipeline, error) {
+	uris := make([]string, 0, len(feeds))
+	for uri := range feeds {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	p := &FeedExportPipeline{}
+	for _, key := range uris {
+		feed := feeds[key]
+		uri := feed.URI
+		if uri == "" {
+			uri = key
+		}
+
+		switch strings.ToLower(feed.Format) {
+		case "", "json":
+			p.feeds = append(p.feeds, NewJSONPipeline(uri))
+		case "csv":
+			p.feeds = append(p.feeds, NewCSVPipeline(uri, feed.Fields))
+		case "xml":
+			p.feeds = append(p.feeds, NewXMLPipeline(uri, ""))
+		default:
+			return nil, fmt.Errorf("unsupported feed format %q: %s", feed.Format, uri)
+		}
+	}
+	return p, nil
+}
+
+// ProcessItem 将数据项写入所有输出
+func (p *FeedExportPipeline) ProcessItem(item map[string]interface{}) map[string]interface{} {
+	for _, feed := range p.feeds {
+		feed.ProcessItem(item)
+	}
+	return item
+}
+
+// Open 打开所有输出
+func (p *FeedExportPipeline) Open() error {
+	for _, feed := range p.feeds {
+		if err := feed.Open(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭所有输出
+func (p *FeedExportPipeline) Close() error {
+	var firstErr error
+	for _, feed := range p.feeds {
+		if err := feed.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 )
 
@@ -88,10 +89,16 @@ func (p *JSONPipeline) Open() error {
 	return nil
 }
 
-// Close 关闭管道
+// Close 关闭管道，之后到达的数据项不再写入
 func (p *JSONPipeline) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	
+	p.encoder = nil
 	if p.file != nil {
-		return p.file.Close()
+		file := p.file
+		p.file = nil
+		return file.Close()
 	}
 	return nil
 }
@@ -162,13 +169,19 @@ func (p *CSVPipeline) Open() error {
 	return nil
 }
 
-// Close 关闭管道
+// Close 关闭管道，之后到达的数据项不再写入
 func (p *CSVPipeline) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	
 	if p.writer != nil {
 		p.writer.Flush()
+		p.writer = nil
 	}
 	if p.file != nil {
-		return p.file.Close()
+		file := p.file
+		p.file = nil
+		return file.Close()
 	}
 	return nil
 }
@@ -188,6 +201,30 @@ type XMLItem struct {
 	Data    map[string]interface{} `xml:",any"`
 }
 
+// MarshalXML 按字段名顺序将每个字段写为<item>的子元素，encoding/xml不支持直接编码map
+func (i XMLItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "item"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(i.Data))
+	for key := range i.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := i.Data[key]
+		if _, isMap := value.(map[string]interface{}); isMap {
+			value = fmt.Sprintf("%v", value)
+		}
+		if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
 // NewXMLPipeline 创建XML管道
 func NewXMLPipeline(filename, rootName string) *XMLPipeline {
 	if rootName == "" {
@@ -237,12 +274,18 @@ func (p *XMLPipeline) Open() error {
 	return nil
 }
 
-// Close 关闭管道
+// Close 关闭管道，之后到达的数据项不再写入
 func (p *XMLPipeline) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	
+	p.encoder = nil
 	if p.file != nil {
 		// 写入根元素结束标签
-		p.file.WriteString(fmt.Sprintf("</%s>\n", p.rootName))
-		return p.file.Close()
+		file := p.file
+		p.file = nil
+		file.WriteString(fmt.Sprintf("</%s>\n", p.rootName))
+		return file.Close()
 	}
 	return nil
 }
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"fmt"
+	"scrago/extension"
+	"scrago/middleware"
+	"scrago/pipeline"
+	"scrago/settings"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 注册内置组件，默认顺序见settings.DefaultSettings
+func init() {
+	// 下载中间件
+	RegisterDownloaderMiddleware("RobotsTxtMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		if !s.RobotstxtObey {
+			return nil, nil
+		}
+		// 未指定时与实际发出的请求使用同一User-Agent匹配规则
+		userAgent := s.RobotstxtUserAgent
+		if userAgents := s.UserAgents(); userAgent == "" && len(userAgents) > 0 {
+			userAgent = userAgents[0]
+		}
+		// 下载槽由引擎在ApplySettings时设置，用于应用Crawl-delay
+		return middleware.NewRobotsTxtMiddleware(userAgent).SetFailurePolicy(s.RobotstxtFailurePolicy), nil
+	})
+	RegisterDownloaderMiddleware("UserAgentMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		// 显式设置USER_AGENT时固定使用，否则轮换USER_AGENT_LIST
+		return middleware.NewUserAgentMiddleware(s.UserAgents(), true), nil
+	})
+	RegisterDownloaderMiddleware("ProxyMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		if len(s.ProxyList) == 0 {
+			return nil, nil
+		}
+		return middleware.NewProxyMiddleware(s.ProxyList, true), nil
+	})
+	RegisterDownloaderMiddleware("DelayMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		return middleware.NewDelayMiddleware(s.DownloadDelay, s.RandomizeDownloadDelay), nil
+	})
+	RegisterDownloaderMiddleware("RetryMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		if !s.RetryEnabled {
+			return nil, nil
+		}
+		backoff := middleware.DefaultRetryBackoff()
+		if s.RetryBackoffBase > 0 {
+			backoff.Base = s.RetryBackoffBase
+		}
+		if s.RetryBackoffMax > 0 {
+			backoff.Max = s.RetryBackoffMax
+		}
+		return middleware.NewRetryMiddleware(s.RetryTimes, s.RetryHTTPCodes).SetBackoff(backoff), nil
+	})
+	RegisterDownloaderMiddleware("CookieMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		if !s.CookiesEnabled {
+			return nil, nil
+		}
+		return middleware.NewCookieMiddleware().SetDebug(s.CookiesDebug).SetFile(s.CookiesFile)
+	})
+	RegisterDownloaderMiddleware("HTTPCacheMiddleware", func(s *settings.Settings) (middleware.Middleware, error) {
+		if !s.CacheEnabled {
+			return nil, nil
+		}
+		dir := s.CacheDir
+		if dir == "" {
+			dir = ".scrapy/cache"
+		}
+		storage, err := middleware.NewCacheStorage(s.CacheStorage, dir)
+		if err != nil {
+			return nil, err
+		}
+		policy, err := middleware.NewCachePolicy(s.CachePolicy)
+		if err != nil {
+			return nil, err
+		}
+		return middleware.NewHTTPCacheMiddleware(storage, policy).
+			SetExpire(time.Duration(s.CacheExpire) * time.Second).
+			SetIgnoreHTTPCodes(s.CacheIgnoreHTTPCodes), nil
+	})
+
+	// 爬虫中间件
+	RegisterSpiderMiddleware("RefererMiddleware", func(s *settings.Settings) (middleware.SpiderMiddleware, error) {
+		return middleware.NewRefererMiddleware(), nil
+	})
+	RegisterSpiderMiddleware("URLLengthMiddleware", func(s *settings.Settings) (middleware.SpiderMiddleware, error) {
+		return middleware.NewURLLengthMiddleware(s.URLLengthLimit), nil
+	})
+	RegisterSpiderMiddleware("DepthMiddleware", func(s *settings.Settings) (middleware.SpiderMiddleware, error) {
+		return middleware.NewDepthMiddleware(s.DepthLimit).SetPriority(s.DepthPriority), nil
+	})
+
+	// 数据管道
+	RegisterPipeline("ConsolePipeline", func(s *settings.Settings) (pipeline.Pipeline, error) {
+		return pipeline.NewConsolePipeline(), nil
+	})
+	RegisterPipeline("FeedExportPipeline", func(s *settings.Settings) (pipeline.Pipeline, error) {
+		if len(s.FeedsExport) == 0 {
+			return nil, nil
+		}
+		return pipeline.NewFeedExportPipeline(s.FeedsExport)
+	})
+	// 旧版文件管道，输出文件见legacyFeed
+	RegisterPipeline("JSONPipeline", func(s *settings.Settings) (pipeline.Pipeline, error) {
+		if uri, _, ok := legacyFeed(s, "json"); ok {
+			return pipeline.NewJSONPipeline(uri), nil
+		}
+		return nil, nil
+	})
+	RegisterPipeline("CSVPipeline", func(s *settings.Settings) (pipeline.Pipeline, error) {
+		if uri, fields, ok := legacyFeed(s, "csv"); ok {
+			return pipeline.NewCSVPipeline(uri, fields), nil
+		}
+		return nil, nil
+	})
+	RegisterPipeline("XMLPipeline", func(s *settings.Settings) (pipeline.Pipeline, error) {
+		if uri, _, ok := legacyFeed(s, "xml"); ok {
+			return pipeline.NewXMLPipeline(uri, "items"), nil
+		}
+		return nil, nil
+	})
+
+	// 扩展
+	RegisterExtension("CoreStats", func(s *settings.Settings) (extension.Extension, error) {
+		return extension.NewCoreStats(), nil
+	})
+	RegisterExtension("LogStats", func(s *settings.Settings) (extension.Extension, error) {
+		return extension.NewLogStats(s.LogStatsInterval), nil
+	})
+	RegisterExtension("MemoryUsage", func(s *settings.Settings) (extension.Extension, error) {
+		if !s.MemUsageEnabled {
+			return nil, nil
+		}
+		return extension.NewMemoryUsage(s.MemUsageLimitMB, s.MemUsageWarningMB, s.MemUsageCheckInterval), nil
+	})
+	RegisterExtension("AutoThrottle", func(s *settings.Settings) (extension.Extension, error) {
+		if !s.AutoThrottleEnabled {
+			return nil, nil
+		}
+		return extension.NewAutoThrottle(extension.AutoThrottleOptions{
+			StartDelay:        s.AutoThrottleStartDelay,
+			MinDelay:          s.AutoThrottleMinDelay,
+			MaxDelay:          s.AutoThrottleMaxDelay,
+			TargetConcurrency: s.AutoThrottleTargetConcurrency,
+			Debug:             s.AutoThrottleDebug,
+		}), nil
+	})
+}
+
+// legacyFeed 旧版文件管道的输出：FEEDS_EXPORT中第一个该格式的输出，没有时为items.<format>。
+// 该输出已由启用的FeedExportPipeline写入时返回false，避免两个管道写同一个文件
+func legacyFeed(s *settings.Settings, format string) (uri string, fields []string, ok bool) {
+	keys := make([]string, 0, len(s.FeedsExport))
+	for key := range s.FeedsExport {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		feed := s.FeedsExport[key]
+		if !strings.EqualFold(feed.Format, format) {
+			continue
+		}
+		if order, enabled := s.ItemPipelines["FeedExportPipeline"]; enabled && order >= 0 {
+			fmt.Printf("ℹ️  %s 已由FeedExportPipeline输出，跳过%sPipeline\n", feed.URI, strings.ToUpper(format))
+			return "", nil, false
+		}
+		uri = feed.URI
+		if uri == "" {
+			uri = key
+		}
+		return uri, feed.Fields, true
+	}
+	return "items." + format, nil, true
+}
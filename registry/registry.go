@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"fmt"
+	"scrago/extension"
+	"scrago/middleware"
+	"scrago/pipeline"
+	"scrago/settings"
+	"sort"
+	"sync"
+)
+
+// Kind 组件类型
+type Kind string
+
+// 组件类型，对应settings中的顺序表
+const (
+	KindDownloaderMiddleware Kind = "downloader_middleware" // DOWNLOADER_MIDDLEWARES
+	KindSpiderMiddleware     Kind = "spider_middleware"     // SPIDER_MIDDLEWARES
+	KindItemPipeline         Kind = "item_pipeline"         // ITEM_PIPELINES
+	KindExtension            Kind = "extension"             // EXTENSIONS
+)
+
+// Factory 按设置创建组件，返回nil表示按当前设置不启用（如RETRY_ENABLED为false）
+type Factory func(s *settings.Settings) (interface{}, error)
+
+// 全局注册表
+var (
+	factories = make(map[Kind]map[string]Factory)
+	mutex     sync.RWMutex
+)
+
+// Register 按名称注册组件，通常在init()中调用，同名组件后注册的覆盖先注册的
+func Register(kind Kind, name string, factory Factory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if factories[kind] == nil {
+		factories[kind] = make(map[string]Factory)
+	}
+	factories[kind][name] = factory
+}
+
+// RegisterDownloaderMiddleware 注册下载中间件
+func RegisterDownloaderMiddleware(name string, factory func(s *settings.Settings) (middleware.Middleware, error)) {
+	Register(KindDownloaderMiddleware, name, func(s *settings.Settings) (interface{}, error) {
+		m, err := factory(s)
+		if m == nil {
+			return nil, err
+		}
+		return m, err
+	})
+}
+
+// RegisterSpiderMiddleware 注册爬虫中间件
+func RegisterSpiderMiddleware(name string, factory func(s *settings.Settings) (middleware.SpiderMiddleware, error)) {
+	Register(KindSpiderMiddleware, name, func(s *settings.Settings) (interface{}, error) {
+		m, err := factory(s)
+		if m == nil {
+			return nil, err
+		}
+		return m, err
+	})
+}
+
+// RegisterPipeline 注册数据管道
+func RegisterPipeline(name string, factory func(s *settings.Settings) (pipeline.Pipeline, error)) {
+	Register(KindItemPipeline, name, func(s *settings.Settings) (interface{}, error) {
+		p, err := factory(s)
+		if p == nil {
+			return nil, err
+		}
+		return p, err
+	})
+}
+
+// RegisterExtension 注册扩展
+func RegisterExtension(name string, factory func(s *settings.Settings) (extension.Extension, error)) {
+	Register(KindExtension, name, func(s *settings.Settings) (interface{}, error) {
+		ext, err := factory(s)
+		if ext == nil {
+			return nil, err
+		}
+		return ext, err
+	})
+}
+
+// Names 返回已注册的组件名称（按字母顺序）
+func Names(kind Kind) []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	names := make([]string, 0, len(factories[kind]))
+	for name := range factories[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build 按顺序表创建组件：值小的在前，相同时按名称；值为负数（含配置文件中的null）的组件不启用
+func Build(kind Kind, orders map[string]int, s *settings.Settings) ([]interface{}, error) {
+	names := make([]string, 0, len(orders))
+	for name, order := range orders {
+		if order >= 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if orders[names[i]] != orders[names[j]] {
+			return orders[names[i]] < orders[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	components := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		mutex.RLock()
+		factory, ok := factories[kind][name]
+		mutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown %s: %s", kind, name)
+		}
+
+		component, err := factory(s)
+		if err != nil {
+			return nil, fmt.Errorf("create %s %s failed: %w", kind, name, err)
+		}
+		if component != nil {
+			components = append(components, component)
+		}
+	}
+	return components, nil
+}
+
+// DownloaderMiddlewares 按DOWNLOADER_MIDDLEWARES创建下载中间件
+func DownloaderMiddlewares(s *settings.Settings) ([]middleware.Middleware, error) {
+	components, err := Build(KindDownloaderMiddleware, s.DownloaderMiddlewares, s)
+	if err != nil {
+		return nil, err
+	}
+	middlewares := make([]middleware.Middleware, len(components))
+	for i, c := range components {
+		middlewares[i] = c.(middleware.Middleware)
+	}
+	return middlewares, nil
+}
+
+// SpiderMiddlewares 按SPIDER_MIDDLEWARES创建爬虫中间件
+func SpiderMiddlewares(s *settings.Settings) ([]middleware.SpiderMiddleware, error) {
+	components, err := Build(KindSpiderMiddleware, s.SpiderMiddlewares, s)
+	if err != nil {
+		return nil, err
+	}
+	middlewares := make([]middleware.SpiderMiddleware, len(components))
+	for i, c := range components {
+		middlewares[i] = c.(middleware.SpiderMiddleware)
+	}
+	return middlewares, nil
+}
+
+// Pipelines 按ITEM_PIPELINES创建数据管道
+func Pipelines(s *settings.Settings) ([]pipeline.Pipeline, error) {
+	components, err := Build(KindItemPipeline, s.ItemPipelines, s)
+	if err != nil {
+		return nil, err
+	}
+	pipelines := make([]pipeline.Pipeline, len(components))
+	for i, c := range components {
+		pipelines[i] = c.(pipeline.Pipeline)
+	}
+	return pipelines, nil
+}
+
+// Extensions 按EXTENSIONS创建扩展
+func Extensions(s *settings.Settings) ([]extension.Extension, error) {
+	components, err := Build(KindExtension, s.Extensions, s)
+	if err != nil {
+		return nil, err
+	}
+	extensions := make([]extension.Extension, len(components))
+	for i, c := range components {
+		extensions[i] = c.(extension.Extension)
+	}
+	return extensions, nil
+}
@@ -0,0 +1,135 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 表单编码类型
+const (
+	FormURLEncoded = "application/x-www-form-urlencoded"
+	FormMultipart  = "multipart/form-data"
+)
+
+// NewFormRequest 创建表单请求
+//
+// GET和HEAD请求的字段编码为URL查询参数，与浏览器一致替换已有的参数并去掉片段，
+// 其他方法的字段以application/x-www-form-urlencoded编码到请求体。
+func NewFormRequest(method, rawURL string, formData url.Values) *Request {
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	if method == http.MethodGet || method == http.MethodHead {
+		if u, err := url.Parse(rawURL); err == nil {
+			u.RawQuery = formData.Encode()
+			u.Fragment = ""
+			u.RawFragment = ""
+			rawURL = u.String()
+		}
+		return NewRequest(method, rawURL)
+	}
+
+	req := NewRequest(method, rawURL)
+	req.Body = []byte(formData.Encode())
+	req.SetHeader("Content-Type", FormURLEncoded)
+	return req
+}
+
+// NewMultipartFormRequest 创建以multipart/form-data编码字段的POST请求
+func NewMultipartFormRequest(rawURL string, formData url.Values) *Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writeFormFields(writer, formData)
+	writer.Close()
+
+	req := NewRequest(http.MethodPost, rawURL)
+	req.Body = body.Bytes()
+	req.SetHeader("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// FilePart multipart请求中的文件
+type FilePart struct {
+	FieldName   string    // 表单字段名
+	FileName    string    // 文件名
+	ContentType string    // 为空时按文件扩展名推断，无法推断时为application/octet-stream
+	Reader      io.Reader // 文件内容，由调用方负责关闭
+}
+
+// NewMultipartRequest 创建包含普通字段和文件的multipart/form-data POST请求
+//
+// 字段按名称顺序写在文件之前，文件按传入顺序写入；读取文件内容失败时返回错误。
+func NewMultipartRequest(rawURL string, fields url.Values, files ...FilePart) (*Request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writeFormFields(writer, fields)
+
+	for _, file := range files {
+		if file.FieldName == "" {
+			return nil, fmt.Errorf("multipart file %q: empty field name", file.FileName)
+		}
+		if file.Reader == nil {
+			return nil, fmt.Errorf("multipart file %q: nil reader", file.FieldName)
+		}
+
+		part, err := writer.CreatePart(filePartHeader(file))
+		if err != nil {
+			return nil, fmt.Errorf("multipart file %q: %w", file.FieldName, err)
+		}
+		if _, err := io.Copy(part, file.Reader); err != nil {
+			return nil, fmt.Errorf("read multipart file %q: %w", file.FieldName, err)
+		}
+	}
+	writer.Close()
+
+	req := NewRequest(http.MethodPost, rawURL)
+	req.Body = body.Bytes()
+	req.SetHeader("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+// quoteEscaper 转义Content-Disposition参数中的引号和反斜杠，与mime/multipart一致
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// filePartHeader 文件部分的头部，multipart.Writer.CreateFormFile不支持指定Content-Type
+func filePartHeader(file FilePart) textproto.MIMEHeader {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(file.FileName))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(file.FieldName), quoteEscaper.Replace(file.FileName)))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+// writeFormFields 按字段名顺序写入multipart字段，写入bytes.Buffer不会失败
+func writeFormFields(writer *multipart.Writer, formData url.Values) {
+	names := make([]string, 0, len(formData))
+	for name := range formData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range formData[name] {
+			writer.WriteField(name, value)
+		}
+	}
+}
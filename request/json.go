@@ -0,0 +1,70 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// JSON请求使用的媒体类型
+const (
+	ContentTypeJSON = "application/json"
+
+	// jsonAccept 与浏览器发起XHR请求JSON时一致
+	jsonAccept = "application/json, text/javascript, */*; q=0.01"
+	// graphQLAccept GraphQL over HTTP规范推荐的Accept，兼容只返回application/json的服务
+	graphQLAccept = "application/graphql-response+json, application/json"
+)
+
+// NewJSONRequest 创建JSON请求
+//
+// data不为nil时序列化为请求体并设置Content-Type: application/json，
+// data可以是任意可序列化的值，json.RawMessage原样发送。
+// 总是设置Accept为JSON；method为空时有data为POST，否则为GET。
+func NewJSONRequest(method, rawURL string, data interface{}) (*Request, error) {
+	method = strings.ToUpper(method)
+	if method == "" {
+		if data != nil {
+			method = http.MethodPost
+		} else {
+			method = http.MethodGet
+		}
+	}
+
+	req := NewRequest(method, rawURL)
+	req.SetHeader("Accept", jsonAccept)
+	if data == nil {
+		return req, nil
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal json body: %w", err)
+	}
+	req.Body = body
+	req.SetHeader("Content-Type", ContentTypeJSON)
+	return req, nil
+}
+
+// graphQLBody GraphQL请求体
+type graphQLBody struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// NewGraphQLRequest 创建GraphQL请求，以POST发送{"query": ..., "variables": ...}
+//
+// variables为空时不发送variables字段。响应中的errors需由回调检查，
+// GraphQL服务通常在查询出错时也返回200。
+func NewGraphQLRequest(endpoint, query string, variables map[string]interface{}) (*Request, error) {
+	req, err := NewJSONRequest(http.MethodPost, endpoint, graphQLBody{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.SetHeader("Accept", graphQLAccept)
+	return req, nil
+}
@@ -6,6 +6,23 @@ import (
 	"time"
 )
 
+// 引擎和下载器维护的Meta键
+const (
+	MetaDepth           = "depth"            // 距起始请求的深度（int）
+	MetaRetryTimes      = "retry_times"      // 已重试次数（int）
+	MetaDownloadLatency = "download_latency" // 下载耗时（time.Duration）
+	MetaRedirectURLs    = "redirect_urls"    // 重定向经过的URL（[]string）
+	MetaProxy           = "proxy"            // 实际使用的代理
+	MetaDownloadSlot    = "download_slot"    // 指定请求使用的下载槽（string）
+	MetaDontObeyRobotsTxt = "dont_obey_robotstxt" // 为true时跳过robots.txt检查（bool）
+	MetaAllowOffsite      = "allow_offsite"       // 为true时跳过站外过滤（bool）
+	MetaDontCache         = "dont_cache"          // 为true时跳过HTTP缓存（bool）
+	MetaCached            = "cached"              // 响应来自HTTP缓存时为true（bool）
+	MetaCookieJar         = "cookiejar"           // 使用的Cookie会话，不同的值对应不同的Cookie Jar
+	MetaDontMergeCookies  = "dont_merge_cookies"  // 为true时不使用也不保存Cookie Jar（bool）
+	MetaEncoding          = "encoding"            // 指定响应的字符编码，跳过编码检测（string）
+)
+
 // Request 请求结构
 type Request struct {
 	Method   string
@@ -16,12 +33,25 @@ type Request struct {
 	Cookies  []*http.Cookie
 	Priority int
 	
+	// 下载时使用的Cookie Jar，由Cookie中间件按Meta["cookiejar"]设置，不参与序列化
+	CookieJar http.CookieJar
+	
 	// 重试相关
 	RetryTimes int
 	DontRetry  bool
 	
+	// 跳过去重过滤
+	DontFilter bool
+	
 	// 回调函数
-	Callback string
+	// Callback/Errback 为回调名称，按名称在爬虫上查找同名方法（首字母不区分大小写）
+	// CallbackFunc 的实际类型为 func(*response.Response) []interface{}
+	// ErrbackFunc 的实际类型为 func(*request.Request, error) []interface{}
+	// 由于response包依赖request包，这里以interface{}保存，由引擎做类型检查
+	Callback     string
+	CallbackFunc interface{}
+	Errback      string
+	ErrbackFunc  interface{}
 	
 	// 代理设置
 	Proxy string
@@ -59,6 +89,9 @@ func (r *Request) AddHeader(key, value string) *Request {
 
 // SetMeta 设置元数据
 func (r *Request) SetMeta(key string, value interface{}) *Request {
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
 	r.Meta[key] = value
 	return r
 }
@@ -68,12 +101,53 @@ func (r *Request) GetMeta(key string) interface{} {
 	return r.Meta[key]
 }
 
+// Depth 获取请求深度，未设置时为0
+func (r *Request) Depth() int {
+	switch v := r.Meta[MetaDepth].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
 // SetPriority 设置优先级
 func (r *Request) SetPriority(priority int) *Request {
 	r.Priority = priority
 	return r
 }
 
+// SetDontFilter 设置是否跳过去重过滤
+func (r *Request) SetDontFilter(dontFilter bool) *Request {
+	r.DontFilter = dontFilter
+	return r
+}
+
+// SetCallback 设置回调，可传入回调名称或 func(*response.Response) []interface{}
+func (r *Request) SetCallback(callback interface{}) *Request {
+	if name, ok := callback.(string); ok {
+		r.Callback = name
+		r.CallbackFunc = nil
+	} else {
+		r.CallbackFunc = callback
+	}
+	return r
+}
+
+// SetErrback 设置下载失败回调，可传入回调名称或 func(*request.Request, error) []interface{}
+func (r *Request) SetErrback(errback interface{}) *Request {
+	if name, ok := errback.(string); ok {
+		r.Errback = name
+		r.ErrbackFunc = nil
+	} else {
+		r.ErrbackFunc = errback
+	}
+	return r
+}
+
 // SetProxy 设置代理
 func (r *Request) SetProxy(proxy string) *Request {
 	r.Proxy = proxy
@@ -103,10 +177,15 @@ func (r *Request) Copy() *Request {
 		Priority:     r.Priority,
 		RetryTimes:   r.RetryTimes,
 		DontRetry:    r.DontRetry,
+		DontFilter:   r.DontFilter,
 		Callback:     r.Callback,
+		CallbackFunc: r.CallbackFunc,
+		Errback:      r.Errback,
+		ErrbackFunc:  r.ErrbackFunc,
 		Proxy:        r.Proxy,
 		Timeout:      r.Timeout,
 		DontRedirect: r.DontRedirect,
+		CookieJar:    r.CookieJar,
 	}
 	
 	// 复制Headers
@@ -0,0 +1,122 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// requestJSON 请求的JSON表示，回调以名称保存
+type requestJSON struct {
+	Method       string                 `json:"method"`
+	URL          string                 `json:"url"`
+	Headers      http.Header            `json:"headers,omitempty"`
+	Body         []byte                 `json:"body,omitempty"`
+	Meta         map[string]interface{} `json:"meta,omitempty"`
+	Cookies      []*http.Cookie         `json:"cookies,omitempty"`
+	Priority     int                    `json:"priority,omitempty"`
+	RetryTimes   int                    `json:"retry_times,omitempty"`
+	DontRetry    bool                   `json:"dont_retry,omitempty"`
+	DontFilter   bool                   `json:"dont_filter,omitempty"`
+	Callback     string                 `json:"callback,omitempty"`
+	Errback      string                 `json:"errback,omitempty"`
+	Proxy        string                 `json:"proxy,omitempty"`
+	Timeout      time.Duration          `json:"timeout,omitempty"`
+	DontRedirect bool                   `json:"dont_redirect,omitempty"`
+}
+
+// MarshalJSON 序列化请求
+// 函数回调只能是爬虫的方法，保存为方法名，反序列化后由引擎按名称查找；
+// Meta中的值需可JSON序列化，反序列化后数字统一变为float64
+func (r *Request) MarshalJSON() ([]byte, error) {
+	callback := r.Callback
+	if r.CallbackFunc != nil {
+		name, err := methodName(r.CallbackFunc)
+		if err != nil {
+			return nil, fmt.Errorf("serialize callback of %s: %w", r.URL, err)
+		}
+		callback = name
+	}
+
+	errback := r.Errback
+	if r.ErrbackFunc != nil {
+		name, err := methodName(r.ErrbackFunc)
+		if err != nil {
+			return nil, fmt.Errorf("serialize errback of %s: %w", r.URL, err)
+		}
+		errback = name
+	}
+
+	return json.Marshal(&requestJSON{
+		Method:       r.Method,
+		URL:          r.URL,
+		Headers:      r.Headers,
+		Body:         r.Body,
+		Meta:         r.Meta,
+		Cookies:      r.Cookies,
+		Priority:     r.Priority,
+		RetryTimes:   r.RetryTimes,
+		DontRetry:    r.DontRetry,
+		DontFilter:   r.DontFilter,
+		Callback:     callback,
+		Errback:      errback,
+		Proxy:        r.Proxy,
+		Timeout:      r.Timeout,
+		DontRedirect: r.DontRedirect,
+	})
+}
+
+// UnmarshalJSON 反序列化请求
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var rj requestJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+
+	*r = Request{
+		Method:       rj.Method,
+		URL:          rj.URL,
+		Headers:      rj.Headers,
+		Body:         rj.Body,
+		Meta:         rj.Meta,
+		Cookies:      rj.Cookies,
+		Priority:     rj.Priority,
+		RetryTimes:   rj.RetryTimes,
+		DontRetry:    rj.DontRetry,
+		DontFilter:   rj.DontFilter,
+		Callback:     rj.Callback,
+		Errback:      rj.Errback,
+		Proxy:        rj.Proxy,
+		Timeout:      rj.Timeout,
+		DontRedirect: rj.DontRedirect,
+	}
+
+	if r.Headers == nil {
+		r.Headers = make(http.Header)
+	}
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
+
+	return nil
+}
+
+// methodName 获取方法值的方法名，如 (*DoubanMovieSpider).ParseMovieDetail-fm 得到 ParseMovieDetail
+func methodName(fn interface{}) (string, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "", fmt.Errorf("not a function: %T", fn)
+	}
+
+	fullName := runtime.FuncForPC(v.Pointer()).Name()
+	if !strings.HasSuffix(fullName, "-fm") {
+		return "", fmt.Errorf("%s is not a spider method", fullName)
+	}
+
+	fullName = strings.TrimSuffix(fullName, "-fm")
+	return fullName[strings.LastIndex(fullName, ".")+1:], nil
+}
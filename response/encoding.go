@@ -0,0 +1,280 @@
+package response
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+const (
+	// metaPrescanLength HTML规范中<meta>编码声明须位于前1024字节
+	metaPrescanLength = 1024
+	// sniffLength 内容嗅探检查的字节数
+	sniffLength = 8 * 1024
+	// sniffMinScore 候选编码的得分低于该值时按windows-1252处理
+	sniffMinScore = 0.7
+)
+
+// boms 字节顺序标记及对应的编码
+var boms = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte("\xef\xbb\xbf"), "utf-8"},
+	{[]byte("\xfe\xff"), "utf-16be"},
+	{[]byte("\xff\xfe"), "utf-16le"},
+}
+
+// DetectEncoding 检测响应体的字符编码，返回WHATWG规范名称（如utf-8、gbk、shift_jis）
+//
+// 依次使用Content-Type中的charset、BOM、前1024字节中的<meta charset>或
+// <meta http-equiv="Content-Type">，都没有时根据内容推测。
+func DetectEncoding(headers http.Header, body []byte) string {
+	if name := lookupEncoding(charsetParam(headers.Get("Content-Type"))); name != "" {
+		return name
+	}
+	if name := bomEncoding(body); name != "" {
+		return name
+	}
+	if name := metaCharset(body); name != "" {
+		return name
+	}
+	return sniffEncoding(body)
+}
+
+// lookupEncoding 编码标签对应的规范名称，不支持的标签返回空字符串
+func lookupEncoding(label string) string {
+	if label == "" {
+		return ""
+	}
+	_, name := charset.Lookup(label)
+	return name
+}
+
+// charsetParam 从Content-Type取charset参数，兼容引号和不规范的写法
+func charsetParam(contentType string) string {
+	i := strings.Index(strings.ToLower(contentType), "charset=")
+	if i < 0 {
+		return ""
+	}
+	value := contentType[i+len("charset="):]
+	if j := strings.IndexAny(value, "; "); j >= 0 {
+		value = value[:j]
+	}
+	return strings.Trim(value, `"' `)
+}
+
+// bomEncoding 按BOM判断编码
+func bomEncoding(body []byte) string {
+	for _, b := range boms {
+		if bytes.HasPrefix(body, b.bom) {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// metaCharset 查找<meta>中声明的编码
+func metaCharset(body []byte) string {
+	if len(body) > metaPrescanLength {
+		body = body[:metaPrescanLength]
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag, hasAttr := z.TagName()
+			if string(tag) != "meta" || !hasAttr {
+				continue
+			}
+
+			var label, content string
+			httpEquiv := false
+			for more := true; more; {
+				var key, val []byte
+				key, val, more = z.TagAttr()
+				switch string(key) {
+				case "charset":
+					label = string(val)
+				case "content":
+					content = string(val)
+				case "http-equiv":
+					httpEquiv = strings.EqualFold(string(val), "content-type")
+				}
+			}
+			if label == "" && httpEquiv {
+				label = charsetParam(content)
+			}
+
+			name := lookupEncoding(label)
+			if strings.HasPrefix(name, "utf-16") {
+				// 能解析出ASCII的<meta>说明不是UTF-16，按规范改用UTF-8
+				name = "utf-8"
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+}
+
+// sniffCandidate 内容嗅探的候选编码，expected判断字符是否属于该编码常见的文字
+type sniffCandidate struct {
+	name     string
+	expected func(r rune) bool
+}
+
+// sniffCandidates 按顺序尝试，得分相同时先出现的优先：
+// GBK编码的韩文也能解码出汉字，Shift_JIS编码的日文也能按GBK解码，因此GBK排在后面；
+// GBK编码的中文按EUC-JP解码同样是汉字，因此EUC-JP排在GBK之后，靠假名区分
+var sniffCandidates = []sniffCandidate{
+	{"shift_jis", func(r rune) bool { return isKana(r) || isHan(r) || isCJKSymbol(r) }},
+	{"euc-kr", func(r rune) bool { return isHangul(r) || isCJKSymbol(r) }},
+	{"gbk", func(r rune) bool { return isHan(r) || isCJKSymbol(r) }},
+	{"euc-jp", func(r rune) bool { return isKana(r) || isHan(r) || isCJKSymbol(r) }},
+	{"big5", func(r rune) bool { return isHan(r) || isCJKSymbol(r) }},
+}
+
+// sniffEncoding 根据内容推测编码
+//
+// 合法的UTF-8（含纯ASCII）按UTF-8处理；非ASCII字节大多单独出现的按windows-1252处理；
+// 否则用候选的东亚编码逐一解码，选择常见文字占非ASCII字符比例最高的编码。
+func sniffEncoding(body []byte) string {
+	if len(body) > sniffLength {
+		body = body[:sniffLength]
+		// 去掉末尾被截断的UTF-8字符
+		for i := len(body) - 1; i >= 0 && i > len(body)-4; i-- {
+			if utf8.RuneStart(body[i]) {
+				if !utf8.FullRune(body[i:]) {
+					body = body[:i]
+				}
+				break
+			}
+		}
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+	if isSingleByte(body) {
+		return "windows-1252"
+	}
+
+	best, bestScore := "windows-1252", sniffMinScore
+	for _, candidate := range sniffCandidates {
+		enc, _ := charset.Lookup(candidate.name)
+		text, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			continue
+		}
+
+		var total, expected int
+		for _, r := range string(text) {
+			if r < utf8.RuneSelf {
+				continue
+			}
+			total++
+			if candidate.expected(r) {
+				expected++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		if score := float64(expected) / float64(total); score > bestScore {
+			best, bestScore = candidate.name, score
+		}
+	}
+	return best
+}
+
+// isSingleByte 非ASCII字节是否大多前后都是ASCII，西欧文字的单字节编码是这种分布
+func isSingleByte(body []byte) bool {
+	var high, isolated int
+	for i, b := range body {
+		if b < utf8.RuneSelf {
+			continue
+		}
+		high++
+		prevASCII := i == 0 || body[i-1] < utf8.RuneSelf
+		nextASCII := i == len(body)-1 || body[i+1] < utf8.RuneSelf
+		if prevASCII && nextASCII {
+			isolated++
+		}
+	}
+	return isolated*2 > high
+}
+
+func isHan(r rune) bool {
+	return r >= 0x4e00 && r <= 0x9fff
+}
+
+func isKana(r rune) bool {
+	return r >= 0x3040 && r <= 0x30ff
+}
+
+func isHangul(r rune) bool {
+	return r >= 0xac00 && r <= 0xd7a3
+}
+
+// isCJKSymbol 东亚文本中常见的标点和全角字符
+func isCJKSymbol(r rune) bool {
+	return (r >= 0x2010 && r <= 0x206f) || // 通用标点，如“”…—
+		(r >= 0x3000 && r <= 0x303f) || // 中日韩标点，如、。《》
+		(r >= 0xff01 && r <= 0xff5e) // 全角ASCII，如，！？
+}
+
+// decodeBody 按编码将响应体转为UTF-8文本，去掉BOM；不支持的编码按UTF-8处理
+func decodeBody(body []byte, name string) string {
+	enc, canonical := charset.Lookup(name)
+	for _, b := range boms {
+		if b.name == canonical && bytes.HasPrefix(body, b.bom) {
+			body = body[len(b.bom):]
+			break
+		}
+	}
+	if enc == nil || canonical == "utf-8" {
+		return string(body)
+	}
+
+	text, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body)
+	}
+	return string(text)
+}
+
+// encodeFormData 将表单字段转为页面编码，与浏览器一致：页面编码无法表示的字符转为&#NNNN;
+func encodeFormData(formData url.Values, name string) url.Values {
+	enc, canonical := charset.Lookup(name)
+	if enc == nil || strings.HasPrefix(canonical, "utf-") {
+		// UTF-16页面的表单浏览器也以UTF-8提交
+		return formData
+	}
+
+	encoder := encoding.HTMLEscapeUnsupported(enc.NewEncoder())
+	encoded := make(url.Values, len(formData))
+	for key, values := range formData {
+		encodedKey, err := encoder.String(key)
+		if err != nil {
+			encodedKey = key
+		}
+		for _, value := range values {
+			encodedValue, err := encoder.String(value)
+			if err != nil {
+				encodedValue = value
+			}
+			encoded[encodedKey] = append(encoded[encodedKey], encodedValue)
+		}
+	}
+	return encoded
+}
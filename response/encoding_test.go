@@ -0,0 +1,71 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("encode %q: %v", text, err)
+	}
+	return data
+}
+
+func TestSniffEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+		text string
+		want string
+	}{
+		{"gbk", simplifiedchinese.GBK, "<p>豆瓣电影，记录你看过的电影。这里有最新的影评和评分。</p>", "gbk"},
+		{"shift_jis", japanese.ShiftJIS, "<p>今日はとても良い天気ですね。映画を見に行きましょう。</p>", "shift_jis"},
+		{"euc-kr", korean.EUCKR, "<p>안녕하세요. 오늘은 날씨가 정말 좋네요. 영화를 보러 갑시다.</p>", "euc-kr"},
+		{"big5", traditionalchinese.Big5, "<p>繁體中文網頁，這裡有最新的電影評論與資訊。</p>", "big5"},
+		{"euc-jp", japanese.EUCJP, "<p>今日はとても良い天気ですね。映画を見に行きましょう。</p>", "euc-jp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := encode(t, tt.enc, tt.text)
+			if got := sniffEncoding(body); got != tt.want {
+				t.Errorf("sniffEncoding() = %q, want %q", got, tt.want)
+			}
+			if got := DetectEncoding(http.Header{}, body); got != tt.want {
+				t.Errorf("DetectEncoding() = %q, want %q", got, tt.want)
+			}
+			if got := decodeBody(body, tt.want); got != tt.text {
+				t.Errorf("decodeBody() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestSniffEncodingFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"ascii", []byte("<p>hello</p>"), "utf-8"},
+		{"utf-8", []byte("<p>你好，世界</p>"), "utf-8"},
+		{"latin", []byte("<p>caf\xe9 cr\xe8me br\xfbl\xe9e</p>"), "windows-1252"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffEncoding(tt.body); got != tt.want {
+				t.Errorf("sniffEncoding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"scrago/request"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+)
+
+// FormOptions 从响应中的表单创建请求的选项
+//
+// FormName、FormID、FormCSS、FormXPath按顺序选用第一个非空的条件查找表单，
+// CSS/XPath匹配到表单内的元素时使用包含它的表单；FormNumber在匹配的表单中选择第几个（从0开始）。
+type FormOptions struct {
+	FormName   string
+	FormID     string
+	FormCSS    string
+	FormXPath  string
+	FormNumber int
+
+	// FormData 覆盖表单中的字段，值为空切片时删除该字段
+	FormData url.Values
+
+	// ClickData 按属性选择点击的提交按钮，如 {"name": "search"} 或 {"value": "登录"}，
+	// 为空时点击第一个提交按钮
+	ClickData map[string]string
+	// DontClick 为true时不点击任何提交按钮
+	DontClick bool
+
+	// Method 覆盖表单的method
+	Method string
+}
+
+// FromResponse 由响应中的HTML表单创建请求
+//
+// 预填表单中的隐藏字段和默认值（选中的复选框和单选框、选中的或第一个选项、文本域），
+// 跳过disabled的字段，再用FormData覆盖；点击的提交按钮的name=value一并提交，
+// 按钮的formaction、formmethod优先于表单的属性。action相对于响应URL（或<base href>）解析，
+// enctype为multipart/form-data的POST表单以multipart编码，字段按页面编码提交。
+func FromResponse(resp *Response, opts FormOptions) (*request.Request, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(resp.Text()))
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+
+	form, err := findForm(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	formData := formInputs(form)
+	for name, values := range opts.FormData {
+		if len(values) == 0 {
+			formData.Del(name)
+		} else {
+			formData[name] = values
+		}
+	}
+
+	action, _ := form.Attr("action")
+	method := strings.ToUpper(strings.TrimSpace(form.AttrOr("method", http.MethodGet)))
+	enctype := strings.ToLower(strings.TrimSpace(form.AttrOr("enctype", request.FormURLEncoded)))
+
+	if !opts.DontClick {
+		clicked, err := findClickable(form, opts.ClickData)
+		if err != nil {
+			return nil, err
+		}
+		if clicked != nil {
+			if name := clicked.AttrOr("name", ""); name != "" {
+				if _, overridden := opts.FormData[name]; !overridden {
+					formData.Set(name, clicked.AttrOr("value", ""))
+				}
+			}
+			if formAction, ok := clicked.Attr("formaction"); ok {
+				action = formAction
+			}
+			if formMethod, ok := clicked.Attr("formmethod"); ok {
+				method = strings.ToUpper(strings.TrimSpace(formMethod))
+			}
+			if formEnctype, ok := clicked.Attr("formenctype"); ok {
+				enctype = strings.ToLower(strings.TrimSpace(formEnctype))
+			}
+		}
+	}
+	if opts.Method != "" {
+		method = strings.ToUpper(opts.Method)
+	}
+	if method != http.MethodPost {
+		// 表单只支持GET和POST，其他值按GET处理
+		method = http.MethodGet
+	}
+
+	// 浏览器按页面编码提交表单
+	formData = encodeFormData(formData, resp.Encoding)
+
+	actionURL := resolveFormAction(resp, doc, action)
+	if method == http.MethodPost && enctype == request.FormMultipart {
+		return request.NewMultipartFormRequest(actionURL, formData), nil
+	}
+	return request.NewFormRequest(method, actionURL, formData), nil
+}
+
+// findForm 按选项查找表单
+func findForm(doc *goquery.Document, opts FormOptions) (*goquery.Selection, error) {
+	var forms *goquery.Selection
+	var desc string
+
+	switch {
+	case opts.FormName != "":
+		desc = "name=" + opts.FormName
+		forms = doc.Find("form").FilterFunction(func(_ int, s *goquery.Selection) bool {
+			return s.AttrOr("name", "") == opts.FormName
+		})
+	case opts.FormID != "":
+		desc = "id=" + opts.FormID
+		forms = doc.Find("form").FilterFunction(func(_ int, s *goquery.Selection) bool {
+			return s.AttrOr("id", "") == opts.FormID
+		})
+	case opts.FormCSS != "":
+		desc = "css=" + opts.FormCSS
+		forms = doc.Find(opts.FormCSS).Closest("form")
+	case opts.FormXPath != "":
+		desc = "xpath=" + opts.FormXPath
+		if len(doc.Nodes) == 0 {
+			return nil, fmt.Errorf("no form found: %s", desc)
+		}
+		nodes, err := htmlquery.QueryAll(doc.Nodes[0], opts.FormXPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid form xpath %q: %w", opts.FormXPath, err)
+		}
+		forms = doc.FindNodes(nodes...).Closest("form")
+	default:
+		desc = "form"
+		forms = doc.Find("form")
+	}
+
+	if forms.Length() == 0 {
+		return nil, fmt.Errorf("no form found: %s", desc)
+	}
+	if opts.FormNumber < 0 || opts.FormNumber >= forms.Length() {
+		return nil, fmt.Errorf("form number %d out of range, %d form(s) found: %s", opts.FormNumber, forms.Length(), desc)
+	}
+	return forms.Eq(opts.FormNumber), nil
+}
+
+// formInputs 表单中会被提交的字段及其默认值
+func formInputs(form *goquery.Selection) url.Values {
+	values := url.Values{}
+
+	form.Find("input, select, textarea").Each(func(_ int, field *goquery.Selection) {
+		name := field.AttrOr("name", "")
+		if name == "" || isDisabled(field) {
+			return
+		}
+
+		switch goquery.NodeName(field) {
+		case "input":
+			switch strings.ToLower(field.AttrOr("type", "text")) {
+			case "submit", "image", "reset", "button", "file":
+				// 提交按钮由点击决定，文件需要通过FormData或multipart请求提供
+				return
+			case "checkbox", "radio":
+				if _, checked := field.Attr("checked"); !checked {
+					return
+				}
+				values.Add(name, field.AttrOr("value", "on"))
+			default:
+				values.Add(name, field.AttrOr("value", ""))
+			}
+		case "select":
+			_, multiple := field.Attr("multiple")
+			options := field.Find("option")
+			selected := options.FilterFunction(func(_ int, option *goquery.Selection) bool {
+				_, ok := option.Attr("selected")
+				return ok
+			})
+			if selected.Length() == 0 && !multiple {
+				// 单选下拉框没有选中项时浏览器提交第一个选项
+				selected = options.First()
+			}
+			if !multiple {
+				selected = selected.First()
+			}
+			selected.Each(func(_ int, option *goquery.Selection) {
+				values.Add(name, optionValue(option))
+			})
+		case "textarea":
+			values.Add(name, field.Text())
+		}
+	})
+	return values
+}
+
+// findClickable 查找要点击的提交按钮，表单没有提交按钮时返回nil
+func findClickable(form *goquery.Selection, clickData map[string]string) (*goquery.Selection, error) {
+	clickables := form.Find("input, button").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		if isDisabled(s) {
+			return false
+		}
+		inputType := strings.ToLower(s.AttrOr("type", ""))
+		if goquery.NodeName(s) == "button" {
+			return inputType == "" || inputType == "submit"
+		}
+		return inputType == "submit" || inputType == "image"
+	})
+	if clickables.Length() == 0 {
+		return nil, nil
+	}
+	if len(clickData) == 0 {
+		return clickables.First(), nil
+	}
+
+	matched := clickables.FilterFunction(func(_ int, s *goquery.Selection) bool {
+		for attr, want := range clickData {
+			if s.AttrOr(attr, "") != want {
+				return false
+			}
+		}
+		return true
+	})
+	if matched.Length() == 0 {
+		return nil, fmt.Errorf("no clickable element matching %v", clickData)
+	}
+	return matched.First(), nil
+}
+
+// resolveFormAction 相对于<base href>或响应URL解析表单的action，action为空时提交到当前页面
+func resolveFormAction(resp *Response, doc *goquery.Document, action string) string {
+	action = strings.TrimSpace(action)
+	if action == "" {
+		return resp.URL
+	}
+
+	base, err := url.Parse(resp.URL)
+	if err != nil {
+		return action
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	ref, err := url.Parse(action)
+	if err != nil {
+		return action
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// optionValue 选项的值，没有value属性时为选项文本
+func optionValue(option *goquery.Selection) string {
+	if value, ok := option.Attr("value"); ok {
+		return value
+	}
+	return strings.TrimSpace(option.Text())
+}
+
+// isDisabled 字段是否被禁用（自身或所在的fieldset）
+func isDisabled(s *goquery.Selection) bool {
+	if _, disabled := s.Attr("disabled"); disabled {
+		return true
+	}
+	return s.ParentsFiltered("fieldset[disabled]").Length() > 0
+}
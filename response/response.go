@@ -3,6 +3,7 @@ package response
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"scrago/request"
 	"scrago/selector"
 	"net/http"
@@ -10,51 +11,106 @@ import (
 	"strings"
 )
 
+// Callback 响应回调
+type Callback = func(resp *Response) []interface{}
+
+// Errback 下载失败回调
+type Errback = func(req *request.Request, err error) []interface{}
+
 // Response 响应结构
 type Response struct {
 	URL        string
 	StatusCode int
 	Headers    http.Header
-	Body       []byte
+	Body       []byte // 原始响应体，未转码
 	Request    *request.Request
 	Meta       map[string]interface{}
 	
 	// 编码信息
+	// 响应体的字符编码，创建响应时检测或由请求Meta["encoding"]指定，修改后Text和选择器按新编码转码
 	Encoding string
 	
+	// 缓存的UTF-8文本及其编码
+	text         string
+	textEncoding string
+	
 	// 缓存的选择器
 	selector *selector.Selector
 }
 
 // NewResponse 创建新响应
+// 响应的Meta与请求的Meta是同一个map，回调中读写Meta即读写请求的Meta
 func NewResponse(url string, statusCode int, headers http.Header, body []byte, req *request.Request) *Response {
+	var meta map[string]interface{}
+	if req != nil {
+		if req.Meta == nil {
+			req.Meta = make(map[string]interface{})
+		}
+		meta = req.Meta
+	} else {
+		meta = make(map[string]interface{})
+	}
+	
 	return &Response{
 		URL:        url,
 		StatusCode: statusCode,
 		Headers:    headers,
 		Body:       body,
 		Request:    req,
-		Meta:       make(map[string]interface{}),
-		Encoding:   "utf-8",
+		Meta:       meta,
+		Encoding:   responseEncoding(headers, body, meta),
 	}
 }
 
-// Text 获取响应文本
+// responseEncoding 请求指定的编码优先，否则自动检测
+func responseEncoding(headers http.Header, body []byte, meta map[string]interface{}) string {
+	if label, _ := meta[request.MetaEncoding].(string); label != "" {
+		if name := lookupEncoding(label); name != "" {
+			return name
+		}
+		fmt.Printf("⚠️  不支持的编码 %q，改为自动检测\n", label)
+	}
+	return DetectEncoding(headers, body)
+}
+
+// Text 获取转码为UTF-8的响应文本，原始字节见Body
 func (r *Response) Text() string {
-	return string(r.Body)
+	if r.Encoding == "" {
+		r.Encoding = DetectEncoding(r.Headers, r.Body)
+	}
+	if r.textEncoding != r.Encoding {
+		r.text = decodeBody(r.Body, r.Encoding)
+		r.textEncoding = r.Encoding
+		r.selector = nil
+	}
+	return r.text
 }
 
-// JSON 解析JSON响应
+// JSON 解析JSON对象响应，顶层不是对象时返回错误，其他情况使用JSONInto
 func (r *Response) JSON() (map[string]interface{}, error) {
 	var result map[string]interface{}
-	err := json.Unmarshal(r.Body, &result)
+	err := r.JSONInto(&result)
 	return result, err
 }
 
+// JSONInto 将JSON响应解析到v，v可以是结构体、切片、map或标量的指针
+// 非UTF-8编码的响应先转码，忽略BOM，解析失败时错误中包含响应URL
+func (r *Response) JSONInto(v interface{}) error {
+	body := bytes.TrimPrefix(r.Body, []byte("\xef\xbb\xbf"))
+	if r.Encoding != "utf-8" {
+		body = []byte(r.Text())
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode json from %s: %w", r.URL, err)
+	}
+	return nil
+}
+
 // Selector 获取选择器
 func (r *Response) Selector() *selector.Selector {
+	text := r.Text()
 	if r.selector == nil {
-		r.selector = selector.NewSelector(string(r.Body))
+		r.selector = selector.NewSelector(text)
 	}
 	return r.selector
 }
@@ -75,6 +131,11 @@ func (r *Response) Follow(href string) *request.Request {
 	return request.NewRequest("GET", absoluteURL)
 }
 
+// FollowWith 跟随链接并指定回调
+func (r *Response) FollowWith(href string, callback Callback) *request.Request {
+	return r.Follow(href).SetCallback(callback)
+}
+
 // FollowAll 跟随所有链接
 func (r *Response) FollowAll(hrefs []string) []*request.Request {
 	requests := make([]*request.Request, 0, len(hrefs))
@@ -105,6 +166,14 @@ func (r *Response) GetMeta(key string) interface{} {
 	return r.Meta[key]
 }
 
+// Depth 获取响应对应请求的深度
+func (r *Response) Depth() int {
+	if r.Request != nil {
+		return r.Request.Depth()
+	}
+	return 0
+}
+
 // SetMeta 设置元数据
 func (r *Response) SetMeta(key string, value interface{}) {
 	r.Meta[key] = value
@@ -0,0 +1,325 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSegmentSize = 10000 // 每个分段文件最多保存的请求数
+	defaultWindowSize  = 256   // 每个队列在内存中预读的请求数
+)
+
+// segmentPath 分段文件路径
+func segmentPath(dir string, id int) string {
+	return filepath.Join(dir, fmt.Sprintf("seg-%08d.jsonl", id))
+}
+
+// listSegments 列出目录中的分段编号（升序）
+func listSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "seg-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		if id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "seg-"), ".jsonl")); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// scanSegment 返回分段文件中完整记录的起始位置及完整部分的长度，并截掉末尾不完整的记录
+func scanSegment(path string) ([]int64, int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var offsets []int64
+	var pos int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		offsets = append(offsets, pos)
+		pos += int64(len(line))
+	}
+
+	// 崩溃时可能留下写了一半的记录
+	if err := file.Truncate(pos); err != nil {
+		return nil, 0, err
+	}
+	return offsets, pos, nil
+}
+
+// fifoSegments 先进先出的分段队列：写入尾部分段，从头部分段顺序读取，读完的分段被删除
+type fifoSegments struct {
+	dir         string
+	segmentSize int
+	windowSize  int
+
+	headID     int
+	headOffset int64 // 已出队记录之后的位置，持久化到state文件
+	reader     *os.File
+	buf        *bufio.Reader
+	readPos    int64
+
+	tailID    int
+	tail      *os.File
+	tailCount int
+
+	window     [][]byte
+	windowEnds []int64
+	count      int
+}
+
+// openFIFOSegments 打开FIFO分段队列
+func openFIFOSegments(dir string, segmentSize, windowSize int) (*fifoSegments, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create queue directory failed: %w", err)
+	}
+
+	q := &fifoSegments{
+		dir:         dir,
+		segmentSize: segmentSize,
+		windowSize:  windowSize,
+	}
+
+	ids, err := listSegments(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read queue directory failed: %w", err)
+	}
+	if len(ids) == 0 {
+		ids = []int{1}
+		if err := os.WriteFile(segmentPath(dir, 1), nil, 0644); err != nil {
+			return nil, fmt.Errorf("create segment failed: %w", err)
+		}
+	}
+
+	q.headID, q.tailID = ids[0], ids[len(ids)-1]
+	q.loadState()
+
+	// 统计剩余记录数
+	for _, id := range ids {
+		offsets, _, err := scanSegment(segmentPath(dir, id))
+		if err != nil {
+			return nil, fmt.Errorf("scan segment failed: %w", err)
+		}
+		for _, off := range offsets {
+			if id > q.headID || off >= q.headOffset {
+				q.count++
+			}
+		}
+		if id == q.tailID {
+			q.tailCount = len(offsets)
+		}
+	}
+
+	if q.tail, err = os.OpenFile(segmentPath(dir, q.tailID), os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return nil, fmt.Errorf("open segment failed: %w", err)
+	}
+	if err := q.openHead(); err != nil {
+		q.tail.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// loadState 读取持久化的读取位置
+func (q *fifoSegments) loadState() {
+	data, err := os.ReadFile(filepath.Join(q.dir, "state"))
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return
+	}
+	id, err1 := strconv.Atoi(fields[0])
+	offset, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil || id < q.headID || id > q.tailID {
+		return
+	}
+
+	if id == q.headID {
+		q.headOffset = offset
+	} else if _, err := os.Stat(segmentPath(q.dir, id)); err == nil {
+		// 之前的分段已读完但未来得及删除
+		for old := q.headID; old < id; old++ {
+			os.Remove(segmentPath(q.dir, old))
+		}
+		q.headID, q.headOffset = id, offset
+	}
+}
+
+// saveState 持久化读取位置
+func (q *fifoSegments) saveState() error {
+	path := filepath.Join(q.dir, "state")
+	data := fmt.Sprintf("%d %d", q.headID, q.headOffset)
+	if err := os.WriteFile(path+".tmp", []byte(data), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// openHead 打开头部分段并定位到读取位置
+func (q *fifoSegments) openHead() error {
+	reader, err := os.Open(segmentPath(q.dir, q.headID))
+	if err != nil {
+		return fmt.Errorf("open segment failed: %w", err)
+	}
+	if _, err := reader.Seek(q.headOffset, io.SeekStart); err != nil {
+		reader.Close()
+		return fmt.Errorf("seek segment failed: %w", err)
+	}
+
+	q.reader = reader
+	q.buf = bufio.NewReader(reader)
+	q.readPos = q.headOffset
+	return nil
+}
+
+// push 追加记录到尾部分段，写满后切换到新分段
+func (q *fifoSegments) push(data []byte) error {
+	if q.tailCount >= q.segmentSize {
+		if err := q.tail.Close(); err != nil {
+			return err
+		}
+		q.tailID++
+		tail, err := os.OpenFile(segmentPath(q.dir, q.tailID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("create segment failed: %w", err)
+		}
+		q.tail = tail
+		q.tailCount = 0
+	}
+
+	if _, err := q.tail.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	q.tailCount++
+	q.count++
+	return nil
+}
+
+// pop 取出头部记录
+func (q *fifoSegments) pop() ([]byte, error) {
+	if q.count == 0 {
+		return nil, nil
+	}
+
+	if len(q.window) == 0 {
+		if err := q.fill(); err != nil {
+			return nil, err
+		}
+		if len(q.window) == 0 {
+			return nil, fmt.Errorf("segment queue is inconsistent: %d records missing", q.count)
+		}
+	}
+
+	data := q.window[0]
+	q.headOffset = q.windowEnds[0]
+	q.window[0] = nil
+	q.window = q.window[1:]
+	q.windowEnds = q.windowEnds[1:]
+	q.count--
+
+	// 队列读空时清空文件，避免无限增长
+	if q.count == 0 && q.headID == q.tailID {
+		if err := q.reset(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	return data, q.saveState()
+}
+
+// fill 从头部分段预读记录到热窗口，头部分段读完时删除并切换到下一个
+func (q *fifoSegments) fill() error {
+	for len(q.window) < q.windowSize {
+		line, err := q.buf.ReadBytes('\n')
+		if err == io.EOF {
+			if q.headID >= q.tailID || len(q.window) > 0 {
+				return nil
+			}
+			if err := q.advanceHead(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		q.readPos += int64(len(line))
+		q.window = append(q.window, bytes.TrimSpace(line))
+		q.windowEnds = append(q.windowEnds, q.readPos)
+	}
+	return nil
+}
+
+// advanceHead 删除已读完的头部分段，切换到下一个分段
+func (q *fifoSegments) advanceHead() error {
+	q.reader.Close()
+	os.Remove(segmentPath(q.dir, q.headID))
+
+	q.headID++
+	q.headOffset = 0
+	if err := q.saveState(); err != nil {
+		return err
+	}
+	return q.openHead()
+}
+
+// reset 队列为空时清空唯一的分段文件
+func (q *fifoSegments) reset() error {
+	if err := q.tail.Truncate(0); err != nil {
+		return err
+	}
+	q.tailCount = 0
+	q.headOffset = 0
+	q.reader.Close()
+	if err := q.saveState(); err != nil {
+		return err
+	}
+	return q.openHead()
+}
+
+// len 剩余记录数
+func (q *fifoSegments) len() int {
+	return q.count
+}
+
+// close 关闭文件
+func (q *fifoSegments) close() error {
+	q.reader.Close()
+	if err := q.saveState(); err != nil {
+		q.tail.Close()
+		return err
+	}
+	return q.tail.Close()
+}
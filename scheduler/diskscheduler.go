@@ -0,0 +1,409 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"scrago/request"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// QueueOrder 磁盘调度器的出队顺序
+type QueueOrder string
+
+const (
+	OrderFIFO     QueueOrder = "fifo"
+	OrderLIFO     QueueOrder = "lifo"
+	OrderPriority QueueOrder = "priority"
+)
+
+// ErrUnserializable 请求无法写入磁盘队列，如回调或错误回调是闭包而不是爬虫方法
+var ErrUnserializable = errors.New("request cannot be serialized")
+
+// DiskScheduler 分段文件持久化调度器
+//
+// 所有请求都以JSON行写入分段文件，内存中只保留少量预读的热窗口，
+// 因此内存占用与队列长度无关。读取位置在每次出队后落盘，进程崩溃后重新打开即可继续。
+// 优先级模式下每个优先级使用独立的FIFO子队列，优先级高的先出队。
+//
+// 回调只按方法名保存，恢复时在爬虫上按名称查找，因此只有爬虫方法（如s.ParseDetail）
+// 作为回调的请求能入队；回调是闭包的请求返回ErrUnserializable，计入scheduler/unserializable。
+type DiskScheduler struct {
+	dir         string
+	order       QueueOrder
+	segmentSize int
+	windowSize  int
+
+	queues     map[int]segmentQueue
+	priorities []int // 按优先级从高到低排序
+	size       int
+	mutex      sync.Mutex
+
+	unserializable int64
+	corrupt        int64
+	broken         map[int]bool // 读取失败的子队列，不再出队和入队
+	onDrop         func(n int)
+}
+
+// segmentQueue 分段文件队列
+type segmentQueue interface {
+	push(data []byte) error
+	pop() ([]byte, error)
+	len() int
+	close() error
+}
+
+// NewDiskScheduler 打开（或创建）磁盘调度器，segmentSize和windowSize为0时使用默认值
+func NewDiskScheduler(dir string, order QueueOrder, segmentSize, windowSize int) (*DiskScheduler, error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSize
+	}
+	if windowSize <= 0 {
+		windowSize = defaultWindowSize
+	}
+
+	switch order {
+	case OrderFIFO, OrderLIFO, OrderPriority:
+	case "":
+		order = OrderFIFO
+	default:
+		return nil, fmt.Errorf("unknown queue order: %s", order)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create queue directory failed: %w", err)
+	}
+
+	s := &DiskScheduler{
+		dir:         dir,
+		order:       order,
+		segmentSize: segmentSize,
+		windowSize:  windowSize,
+		queues:      make(map[int]segmentQueue),
+		broken:      make(map[int]bool),
+	}
+
+	if order == OrderPriority {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read queue directory failed: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "p") {
+				continue
+			}
+			priority, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "p"))
+			if err != nil {
+				continue
+			}
+			if _, err := s.queue(priority); err != nil {
+				s.Close()
+				return nil, err
+			}
+		}
+	} else if _, err := s.queue(0); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// queue 获取（或打开）指定优先级的子队列
+func (s *DiskScheduler) queue(priority int) (segmentQueue, error) {
+	if q, exists := s.queues[priority]; exists {
+		return q, nil
+	}
+
+	var q segmentQueue
+	var err error
+	switch s.order {
+	case OrderLIFO:
+		q, err = openLIFOSegments(s.dir, s.segmentSize)
+	case OrderPriority:
+		q, err = openFIFOSegments(filepath.Join(s.dir, "p"+strconv.Itoa(priority)), s.segmentSize, s.windowSize)
+	default:
+		q, err = openFIFOSegments(s.dir, s.segmentSize, s.windowSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.queues[priority] = q
+	s.priorities = append(s.priorities, priority)
+	sort.Sort(sort.Reverse(sort.IntSlice(s.priorities)))
+	s.size += q.len()
+	return q, nil
+}
+
+// Enqueue 入队
+func (s *DiskScheduler) Enqueue(req *request.Request) bool {
+	if err := s.TryEnqueue(req); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return false
+	}
+	return true
+}
+
+// TryEnqueue 入队，请求无法序列化时返回ErrUnserializable
+func (s *DiskScheduler) TryEnqueue(req *request.Request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		atomic.AddInt64(&s.unserializable, 1)
+		return fmt.Errorf("%w: %v", ErrUnserializable, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	priority := 0
+	if s.order == OrderPriority {
+		priority = req.Priority
+	}
+
+	if s.broken[priority] {
+		return fmt.Errorf("write disk queue failed: queue %d is unreadable", priority)
+	}
+	q, err := s.queue(priority)
+	if err == nil {
+		err = q.push(data)
+	}
+	if err != nil {
+		return fmt.Errorf("write disk queue failed: %w", err)
+	}
+
+	s.size++
+	return nil
+}
+
+// SetDropHandler 设置丢弃损坏记录时的回调，参数为丢弃的记录数。
+// 这些记录已计入Size，调用方（如引擎的在途计数）需要据此扣除
+func (s *DiskScheduler) SetDropHandler(fn func(n int)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onDrop = fn
+}
+
+// Dequeue 出队，跳过无法解析的记录；子队列读取失败时丢弃其剩余的全部记录
+func (s *DiskScheduler) Dequeue() *request.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, priority := range s.priorities {
+		if s.broken[priority] {
+			continue
+		}
+		q := s.queues[priority]
+		for q.len() > 0 {
+			data, err := q.pop()
+			if err != nil {
+				fmt.Printf("❌ 读取磁盘队列失败，丢弃其中剩余的 %d 个请求: %v\n", q.len(), err)
+				s.broken[priority] = true
+				s.drop(q.len())
+				break
+			}
+
+			req := &request.Request{}
+			if err := json.Unmarshal(data, req); err != nil {
+				fmt.Printf("⚠️  跳过无法解析的队列记录: %v\n", err)
+				s.drop(1)
+				continue
+			}
+			s.size--
+			return req
+		}
+	}
+	return nil
+}
+
+// drop 丢弃n条已计入队列大小的记录并通知回调，调用时需持有锁
+func (s *DiskScheduler) drop(n int) {
+	if n <= 0 {
+		return
+	}
+	s.size -= n
+	s.corrupt += int64(n)
+	if s.onDrop != nil {
+		s.onDrop(n)
+	}
+}
+
+// Empty 检查是否为空
+func (s *DiskScheduler) Empty() bool {
+	return s.Size() == 0
+}
+
+// Size 获取队列大小
+func (s *DiskScheduler) Size() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.size
+}
+
+// Stats 磁盘队列统计
+func (s *DiskScheduler) Stats() map[string]int64 {
+	s.mutex.Lock()
+	corrupt := s.corrupt
+	s.mutex.Unlock()
+
+	return map[string]int64{
+		"scheduler/unserializable": atomic.LoadInt64(&s.unserializable),
+		"scheduler/corrupt":        corrupt,
+	}
+}
+
+// Close 关闭调度器
+func (s *DiskScheduler) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var firstErr error
+	for _, q := range s.queues {
+		if err := q.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// lifoSegments 后进先出的分段队列：从尾部分段末尾弹出并截断文件，尾部分段为空时回到上一个分段
+type lifoSegments struct {
+	dir         string
+	segmentSize int
+
+	firstID  int
+	tailID   int
+	tail     *os.File
+	tailSize int64
+	offsets  []int64 // 尾部分段中每条记录的起始位置
+	count    int
+}
+
+// openLIFOSegments 打开LIFO分段队列
+func openLIFOSegments(dir string, segmentSize int) (*lifoSegments, error) {
+	q := &lifoSegments{
+		dir:         dir,
+		segmentSize: segmentSize,
+	}
+
+	ids, err := listSegments(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read queue directory failed: %w", err)
+	}
+	if len(ids) == 0 {
+		ids = []int{1}
+		if err := os.WriteFile(segmentPath(dir, 1), nil, 0644); err != nil {
+			return nil, fmt.Errorf("create segment failed: %w", err)
+		}
+	}
+	q.firstID, q.tailID = ids[0], ids[len(ids)-1]
+
+	for _, id := range ids {
+		offsets, _, err := scanSegment(segmentPath(dir, id))
+		if err != nil {
+			return nil, fmt.Errorf("scan segment failed: %w", err)
+		}
+		q.count += len(offsets)
+	}
+
+	if err := q.openTail(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// openTail 打开尾部分段并建立记录索引
+func (q *lifoSegments) openTail() error {
+	path := segmentPath(q.dir, q.tailID)
+	offsets, size, err := scanSegment(path)
+	if err != nil {
+		return fmt.Errorf("scan segment failed: %w", err)
+	}
+
+	tail, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("open segment failed: %w", err)
+	}
+
+	q.tail = tail
+	q.tailSize = size
+	q.offsets = offsets
+	return nil
+}
+
+// push 追加记录，尾部分段写满后切换到新分段
+func (q *lifoSegments) push(data []byte) error {
+	if len(q.offsets) >= q.segmentSize {
+		if err := q.tail.Close(); err != nil {
+			return err
+		}
+		q.tailID++
+		if err := os.WriteFile(segmentPath(q.dir, q.tailID), nil, 0644); err != nil {
+			return fmt.Errorf("create segment failed: %w", err)
+		}
+		if err := q.openTail(); err != nil {
+			return err
+		}
+	}
+
+	line := append(data, '\n')
+	if _, err := q.tail.WriteAt(line, q.tailSize); err != nil {
+		return err
+	}
+	q.offsets = append(q.offsets, q.tailSize)
+	q.tailSize += int64(len(line))
+	q.count++
+	return nil
+}
+
+// pop 弹出最后一条记录
+func (q *lifoSegments) pop() ([]byte, error) {
+	if q.count == 0 {
+		return nil, nil
+	}
+
+	// 尾部分段已空，删除并回到上一个分段
+	for len(q.offsets) == 0 && q.tailID > q.firstID {
+		q.tail.Close()
+		os.Remove(segmentPath(q.dir, q.tailID))
+		q.tailID--
+		if err := q.openTail(); err != nil {
+			return nil, err
+		}
+	}
+	if len(q.offsets) == 0 {
+		return nil, fmt.Errorf("segment queue is inconsistent: %d records missing", q.count)
+	}
+
+	start := q.offsets[len(q.offsets)-1]
+	data := make([]byte, q.tailSize-start)
+	if _, err := q.tail.ReadAt(data, start); err != nil {
+		return nil, err
+	}
+
+	// 截断文件即完成出队，崩溃后不会重复出队
+	if err := q.tail.Truncate(start); err != nil {
+		return nil, err
+	}
+	q.offsets = q.offsets[:len(q.offsets)-1]
+	q.tailSize = start
+	q.count--
+
+	return bytes.TrimSpace(data), nil
+}
+
+// len 剩余记录数
+func (q *lifoSegments) len() int {
+	return q.count
+}
+
+// close 关闭文件
+func (q *lifoSegments) close() error {
+	return q.tail.Close()
+}
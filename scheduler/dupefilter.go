@@ -0,0 +1,454 @@
+package scheduler
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"scrago/request"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DupeFilter 请求去重过滤器
+type DupeFilter interface {
+	// RequestSeen 返回true表示请求已出现过，只检查不记录
+	RequestSeen(req *request.Request) bool
+	// MarkSeen 记录请求，在请求成功入队后调用
+	MarkSeen(req *request.Request)
+	Close() error
+}
+
+// FingerprintStore 指纹存储后端
+type FingerprintStore interface {
+	// Add 添加指纹，返回true表示是新指纹
+	Add(fp string) bool
+	// Contains 检查指纹是否已存在
+	Contains(fp string) bool
+	Size() int
+	Close() error
+}
+
+// RFPDupeFilter 基于请求指纹的去重过滤器
+type RFPDupeFilter struct {
+	store          FingerprintStore
+	includeHeaders []string
+	filtered       int64
+	logged         int32
+}
+
+// NewRFPDupeFilter 创建指纹去重过滤器，includeHeaders为参与指纹计算的请求头
+func NewRFPDupeFilter(store FingerprintStore, includeHeaders ...string) *RFPDupeFilter {
+	return &RFPDupeFilter{
+		store:          store,
+		includeHeaders: includeHeaders,
+	}
+}
+
+// RequestSeen 检查请求是否重复
+func (f *RFPDupeFilter) RequestSeen(req *request.Request) bool {
+	if !f.store.Contains(Fingerprint(req, f.includeHeaders...)) {
+		return false
+	}
+
+	atomic.AddInt64(&f.filtered, 1)
+	if atomic.CompareAndSwapInt32(&f.logged, 0, 1) {
+		fmt.Printf("🔁 过滤重复请求: %s (后续重复请求不再显示)\n", req.URL)
+	}
+	return true
+}
+
+// MarkSeen 记录请求指纹
+func (f *RFPDupeFilter) MarkSeen(req *request.Request) {
+	f.store.Add(Fingerprint(req, f.includeHeaders...))
+}
+
+// Filtered 被过滤的请求数
+func (f *RFPDupeFilter) Filtered() int64 {
+	return atomic.LoadInt64(&f.filtered)
+}
+
+// Stats 去重统计
+func (f *RFPDupeFilter) Stats() map[string]int64 {
+	return map[string]int64{
+		"dupefilter/filtered": f.Filtered(),
+		"dupefilter/seen":     int64(f.store.Size()),
+	}
+}
+
+// Close 关闭过滤器
+func (f *RFPDupeFilter) Close() error {
+	return f.store.Close()
+}
+
+// Fingerprint 计算请求指纹：方法、规范化URL、请求体及指定请求头
+func Fingerprint(req *request.Request, includeHeaders ...string) string {
+	h := sha1.New()
+	h.Write([]byte(strings.ToUpper(req.Method)))
+	h.Write([]byte{0})
+	h.Write([]byte(CanonicalizeURL(req.URL)))
+	h.Write([]byte{0})
+	h.Write(req.Body)
+	h.Write([]byte{0})
+
+	if len(includeHeaders) > 0 {
+		names := make([]string, 0, len(includeHeaders))
+		for _, name := range includeHeaders {
+			names = append(names, strings.ToLower(name))
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			h.Write([]byte(name))
+			h.Write([]byte{':'})
+			h.Write([]byte(strings.Join(req.Headers.Values(name), ",")))
+			h.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CanonicalizeURL 规范化URL：小写scheme和host、去掉默认端口和片段、查询参数排序
+func CanonicalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = host + ":" + port
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+
+	// 查询参数按键、值排序，保留空值
+	if u.RawQuery != "" {
+		pairs := strings.Split(u.RawQuery, "&")
+		sorted := make([]string, 0, len(pairs))
+		for _, pair := range pairs {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			if k, err := url.QueryUnescape(key); err == nil {
+				key = k
+			}
+			if v, err := url.QueryUnescape(value); err == nil {
+				value = v
+			}
+			sorted = append(sorted, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+		sort.Strings(sorted)
+		u.RawQuery = strings.Join(sorted, "&")
+	}
+
+	return u.String()
+}
+
+// DupeFilterScheduler 带去重的调度器，在入队时丢弃重复请求
+type DupeFilterScheduler struct {
+	Scheduler
+	filter DupeFilter
+	mutex  sync.Mutex
+}
+
+// NewDupeFilterScheduler 为调度器加上去重过滤
+func NewDupeFilterScheduler(inner Scheduler, filter DupeFilter) *DupeFilterScheduler {
+	return &DupeFilterScheduler{
+		Scheduler: inner,
+		filter:    filter,
+	}
+}
+
+// Enqueue 入队，重复请求返回false
+func (s *DupeFilterScheduler) Enqueue(req *request.Request) bool {
+	return s.TryEnqueue(req) == nil
+}
+
+// TryEnqueue 入队，重复请求返回ErrDropped，内部调度器的丢弃原因原样返回；
+// 指纹在入队成功后才记录，入队失败的请求之后仍可再次调度
+func (s *DupeFilterScheduler) TryEnqueue(req *request.Request) error {
+	if req.DontFilter {
+		return TryEnqueue(s.Scheduler, req)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.filter.RequestSeen(req) {
+		return ErrDropped
+	}
+	if err := TryEnqueue(s.Scheduler, req); err != nil {
+		return err
+	}
+	s.filter.MarkSeen(req)
+	return nil
+}
+
+// SetDropHandler 转发给内部调度器
+func (s *DupeFilterScheduler) SetDropHandler(fn func(n int)) {
+	if notifier, ok := s.Scheduler.(DropNotifier); ok {
+		notifier.SetDropHandler(fn)
+	}
+}
+
+// Filter 返回去重过滤器
+func (s *DupeFilterScheduler) Filter() DupeFilter {
+	return s.filter
+}
+
+// Stats 去重统计及内部调度器的统计
+func (s *DupeFilterScheduler) Stats() map[string]int64 {
+	result := map[string]int64{}
+	for _, c := range []interface{}{s.filter, s.Scheduler} {
+		if sp, ok := c.(interface{ Stats() map[string]int64 }); ok {
+			for key, value := range sp.Stats() {
+				result[key] = value
+			}
+		}
+	}
+	return result
+}
+
+// Close 关闭过滤器及内部调度器
+func (s *DupeFilterScheduler) Close() error {
+	if closer, ok := s.Scheduler.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			s.filter.Close()
+			return err
+		}
+	}
+	return s.filter.Close()
+}
+
+// MemoryStore 内存指纹集合
+type MemoryStore struct {
+	seen  map[string]struct{}
+	mutex sync.RWMutex
+}
+
+// NewMemoryStore 创建内存指纹集合
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Add 添加指纹
+func (s *MemoryStore) Add(fp string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.seen[fp]; exists {
+		return false
+	}
+	s.seen[fp] = struct{}{}
+	return true
+}
+
+// Contains 检查指纹是否已存在
+func (s *MemoryStore) Contains(fp string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, exists := s.seen[fp]
+	return exists
+}
+
+// Size 指纹数量
+func (s *MemoryStore) Size() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.seen)
+}
+
+// Close 关闭存储
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// BloomStore 布隆过滤器指纹集合，适合超大规模爬取（存在少量误判）
+type BloomStore struct {
+	bits  []uint64
+	m     uint64
+	k     uint64
+	count int
+	mutex sync.Mutex
+}
+
+// NewBloomStore 按预计元素数和误判率创建布隆过滤器
+func NewBloomStore(expectedItems int, falsePositiveRate float64) *BloomStore {
+	if expectedItems <= 0 {
+		expectedItems = 1000000
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	n := float64(expectedItems)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/n*math.Ln2)))
+
+	return &BloomStore{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// positions 计算指纹对应的比特位
+func (s *BloomStore) positions(fp string) []uint64 {
+	sum := sha1.Sum([]byte(fp))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16])
+
+	result := make([]uint64, s.k)
+	for i := range result {
+		result[i] = (h1 + uint64(i)*h2) % s.m
+	}
+	return result
+}
+
+// Add 添加指纹
+func (s *BloomStore) Add(fp string) bool {
+	positions := s.positions(fp)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := false
+	for _, pos := range positions {
+		word, mask := pos/64, uint64(1)<<(pos%64)
+		if s.bits[word]&mask == 0 {
+			s.bits[word] |= mask
+			added = true
+		}
+	}
+
+	if added {
+		s.count++
+	}
+	return added
+}
+
+// Contains 检查指纹是否可能已存在
+func (s *BloomStore) Contains(fp string) bool {
+	positions := s.positions(fp)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, pos := range positions {
+		if s.bits[pos/64]&(uint64(1)<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Size 已添加的指纹数量（近似）
+func (s *BloomStore) Size() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.count
+}
+
+// Close 关闭存储
+func (s *BloomStore) Close() error {
+	return nil
+}
+
+// FileStore 文件持久化的指纹集合，每行一个指纹，重启后可继续去重
+type FileStore struct {
+	*MemoryStore
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewFileStore 打开（或创建）指纹文件
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create directory failed: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open fingerprint file failed: %w", err)
+	}
+
+	store := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		file:        file,
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fp := strings.TrimSpace(scanner.Text()); fp != "" {
+			store.MemoryStore.Add(fp)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read fingerprint file failed: %w", err)
+	}
+
+	return store, nil
+}
+
+// Add 添加指纹，新指纹同时追加到文件
+func (s *FileStore) Add(fp string) bool {
+	if !s.MemoryStore.Add(fp) {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.WriteString(fp + "\n"); err != nil {
+		fmt.Printf("⚠️  写入指纹文件失败: %v\n", err)
+	}
+	return true
+}
+
+// Close 关闭文件
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+// NewFingerprintStore 按名称创建指纹存储：memory、bloom、file
+func NewFingerprintStore(backend, path string) (FingerprintStore, error) {
+	switch strings.ToLower(backend) {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "bloom":
+		return NewBloomStore(0, 0), nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file dupefilter requires a path")
+		}
+		return NewFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown dupefilter backend: %s", backend)
+	}
+}
@@ -2,19 +2,48 @@ package scheduler
 
 import (
 	"container/heap"
+	"errors"
+	"fmt"
 	"scrago/request"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
 
 // Scheduler 调度器接口
 type Scheduler interface {
-	Enqueue(req *request.Request)
+	// Enqueue 入队，返回false表示请求被丢弃（如重复请求）
+	Enqueue(req *request.Request) bool
 	Dequeue() *request.Request
 	Empty() bool
 	Size() int
 }
 
+// ErrDropped 请求被调度器丢弃（如重复请求）
+var ErrDropped = errors.New("request dropped by scheduler")
+
+// ErrorEnqueuer 入队失败时能说明原因的调度器
+type ErrorEnqueuer interface {
+	TryEnqueue(req *request.Request) error
+}
+
+// DropNotifier 出队时可能丢弃已入队请求（如磁盘队列中损坏的记录）的调度器，
+// 丢弃时调用回调，参数为丢弃的请求数
+type DropNotifier interface {
+	SetDropHandler(fn func(n int))
+}
+
+// TryEnqueue 入队，调度器实现了ErrorEnqueuer时返回具体的丢弃原因，否则返回ErrDropped
+func TryEnqueue(s Scheduler, req *request.Request) error {
+	if e, ok := s.(ErrorEnqueuer); ok {
+		return e.TryEnqueue(req)
+	}
+	if !s.Enqueue(req) {
+		return ErrDropped
+	}
+	return nil
+}
+
 // FIFOScheduler FIFO调度器
 type FIFOScheduler struct {
 	queue []*request.Request
@@ -29,10 +58,11 @@ func NewFIFOScheduler() *FIFOScheduler {
 }
 
 // Enqueue 入队
-func (s *FIFOScheduler) Enqueue(req *request.Request) {
+func (s *FIFOScheduler) Enqueue(req *request.Request) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.queue = append(s.queue, req)
+	return true
 }
 
 // Dequeue 出队
@@ -66,6 +96,7 @@ func (s *FIFOScheduler) Size() int {
 // PriorityScheduler 优先级调度器
 type PriorityScheduler struct {
 	queue PriorityQueue
+	seq   int64
 	mutex sync.RWMutex
 }
 
@@ -77,6 +108,7 @@ type PriorityItem struct {
 	Request  *request.Request
 	Priority int
 	Index    int
+	seq      int64 // 入队序号，同优先级按先进先出
 }
 
 // NewPriorityScheduler 创建优先级调度器
@@ -90,16 +122,19 @@ func NewPriorityScheduler() *PriorityScheduler {
 }
 
 // Enqueue 入队
-func (s *PriorityScheduler) Enqueue(req *request.Request) {
+func (s *PriorityScheduler) Enqueue(req *request.Request) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	
 	item := &PriorityItem{
 		Request:  req,
 		Priority: req.Priority,
+		seq:      s.seq,
 	}
+	s.seq++
 	
 	heap.Push(&s.queue, item)
+	return true
 }
 
 // Dequeue 出队
@@ -135,7 +170,10 @@ func (pq PriorityQueue) Len() int { return len(pq) }
 
 func (pq PriorityQueue) Less(i, j int) bool {
 	// 优先级越高，越先执行（数值越大优先级越高）
-	return pq[i].Priority > pq[j].Priority
+	if pq[i].Priority != pq[j].Priority {
+		return pq[i].Priority > pq[j].Priority
+	}
+	return pq[i].seq < pq[j].seq
 }
 
 func (pq PriorityQueue) Swap(i, j int) {
@@ -175,10 +213,11 @@ func NewLIFOScheduler() *LIFOScheduler {
 }
 
 // Enqueue 入栈
-func (s *LIFOScheduler) Enqueue(req *request.Request) {
+func (s *LIFOScheduler) Enqueue(req *request.Request) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.stack = append(s.stack, req)
+	return true
 }
 
 // Dequeue 出栈
@@ -224,7 +263,7 @@ func NewChannelScheduler(bufferSize int) *ChannelScheduler {
 }
 
 // Enqueue 入队（非阻塞）
-func (s *ChannelScheduler) Enqueue(req *request.Request) {
+func (s *ChannelScheduler) Enqueue(req *request.Request) bool {
 	select {
 	case s.requestChan <- req:
 		atomic.AddInt64(&s.size, 1)
@@ -235,6 +274,7 @@ func (s *ChannelScheduler) Enqueue(req *request.Request) {
 			atomic.AddInt64(&s.size, 1)
 		}()
 	}
+	return true
 }
 
 // Dequeue 出队
@@ -256,4 +296,20 @@ func (s *ChannelScheduler) Empty() bool {
 // Size 获取队列大小
 func (s *ChannelScheduler) Size() int {
 	return int(atomic.LoadInt64(&s.size))
-}
\ No newline at end of file
+}
+
+// NewScheduler 按名称创建内存调度器：channel、fifo、lifo、priority
+func NewScheduler(name string, bufferSize int) (Scheduler, error) {
+	switch strings.ToLower(name) {
+	case "", "channel":
+		return NewChannelScheduler(bufferSize), nil
+	case "fifo":
+		return NewFIFOScheduler(), nil
+	case "lifo":
+		return NewLIFOScheduler(), nil
+	case "priority":
+		return NewPriorityScheduler(), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduler: %s", name)
+	}
+}
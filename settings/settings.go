@@ -3,38 +3,104 @@ package settings
 import (
 	"encoding/json"
 	"io/ioutil"
+	"reflect"
 	"runtime"
+	"strings"
 	"time"
 )
 
+// DefaultUserAgent 默认User-Agent，未修改时轮换UserAgentList
+const DefaultUserAgent = "go-scrapy/1.0 (+https://github.com/go-scrapy/go-scrapy)"
+
 // Settings 爬虫设置配置
 type Settings struct {
 	// 基础设置
 	BotName     string `json:"bot_name"`
 	UserAgent   string `json:"user_agent"`
+	UserAgentList []string `json:"user_agent_list"` // UserAgent为默认值时随机轮换其中的User-Agent
 	RobotstxtObey bool `json:"robotstxt_obey"`
+	RobotstxtUserAgent     string `json:"robotstxt_user_agent"`     // 匹配robots.txt规则的User-Agent，为空时使用UserAgent
+	RobotstxtFailurePolicy string `json:"robotstxt_failure_policy"` // robots.txt获取失败时allow或deny
 	
 	// 并发设置
 	ConcurrentRequests         int `json:"concurrent_requests"`
 	ConcurrentRequestsPerDomain int `json:"concurrent_requests_per_domain"`
+	ConcurrentRequestsPerIP     int `json:"concurrent_requests_per_ip"` // 大于0时按IP限制并发，忽略按域名的限制
+	
+	// 单独配置的下载槽（键为域名或IP）
+	DownloadSlots map[string]DownloadSlotSettings `json:"download_slots"`
 	
 	// 下载设置
 	DownloadDelay         time.Duration `json:"download_delay"`
 	RandomizeDownloadDelay bool         `json:"randomize_download_delay"`
 	DownloadTimeout       time.Duration `json:"download_timeout"`
+	ProxyList             []string      `json:"proxy_list"` // 非空时随机为请求设置其中的代理
+	
+	// 自动限速设置
+	AutoThrottleEnabled           bool          `json:"autothrottle_enabled"`
+	AutoThrottleStartDelay        time.Duration `json:"autothrottle_start_delay"`
+	AutoThrottleMinDelay          time.Duration `json:"autothrottle_min_delay"`
+	AutoThrottleMaxDelay          time.Duration `json:"autothrottle_max_delay"`
+	AutoThrottleTargetConcurrency float64       `json:"autothrottle_target_concurrency"`
+	AutoThrottleDebug             bool          `json:"autothrottle_debug"`
+	
+	// 关闭设置：收到停止信号后等待进行中请求完成的最长时间
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	
+	// 自动关闭设置：达到任一条件时优雅关闭爬虫，为0表示不启用
+	CloseSpiderItemCount  int           `json:"closespider_itemcount"`
+	CloseSpiderPageCount  int           `json:"closespider_pagecount"`
+	CloseSpiderErrorCount int           `json:"closespider_errorcount"`
+	CloseSpiderTimeout    time.Duration `json:"closespider_timeout"`
 	
 	// 重试设置
 	RetryEnabled bool `json:"retry_enabled"`
 	RetryTimes   int  `json:"retry_times"`
 	RetryHTTPCodes []int `json:"retry_http_codes"`
+	RetryBackoffBase time.Duration `json:"retry_backoff_base"`
+	RetryBackoffMax  time.Duration `json:"retry_backoff_max"`
+	
+	// 去重设置
+	DupeFilterEnabled bool   `json:"dupefilter_enabled"`
+	DupeFilterBackend string `json:"dupefilter_backend"` // memory、bloom、file
+	DupeFilterPath    string `json:"dupefilter_path"`
+	
+	// 调度器设置：内存调度器（channel、fifo、lifo、priority），指定JOBDIR时磁盘队列的出队顺序（fifo、lifo、priority）
+	Scheduler          string `json:"scheduler"`
+	SchedulerDiskQueue string `json:"scheduler_disk_queue"`
+	
+	// 持久化目录，用于暂停和恢复爬取
+	JobDir string `json:"jobdir"`
+	
+	// 运行结束后写入统计信息的JSON文件
+	StatsFile string `json:"stats_file"`
+	
+	// 请求URL的最大长度，超过时丢弃请求
+	URLLengthLimit int `json:"urllength_limit"`
 	
-	// 中间件设置
+	// 深度设置：最大爬取深度（0表示不限制），每层深度调整的优先级（正数广度优先，负数深度优先）
+	DepthLimit    int `json:"depth_limit"`
+	DepthPriority int `json:"depth_priority"`
+	
+	// 中间件设置：组件名称到顺序的映射，按值从小到大排列，负数表示不启用
 	DownloaderMiddlewares map[string]int `json:"downloader_middlewares"`
 	SpiderMiddlewares     map[string]int `json:"spider_middlewares"`
 	
 	// 管道设置
 	ItemPipelines map[string]int `json:"item_pipelines"`
 	
+	// 扩展设置：按值从小到大的顺序启用
+	Extensions map[string]int `json:"extensions"`
+	
+	// 爬取速度日志的打印间隔
+	LogStatsInterval time.Duration `json:"logstats_interval"`
+	
+	// 内存监控设置：超过警告值时打印警告，超过上限时关闭爬虫（0表示不启用）
+	MemUsageEnabled       bool          `json:"memusage_enabled"`
+	MemUsageLimitMB       int           `json:"memusage_limit_mb"`
+	MemUsageWarningMB     int           `json:"memusage_warning_mb"`
+	MemUsageCheckInterval time.Duration `json:"memusage_check_interval"`
+	
 	// 输出设置
 	FeedsExport map[string]FeedExportSettings `json:"feeds_export"`
 	
@@ -44,9 +110,17 @@ type Settings struct {
 	LogStdout bool   `json:"log_stdout"`
 	
 	// 缓存设置
-	CacheEnabled bool   `json:"cache_enabled"`
-	CacheExpire  int    `json:"cache_expire"`
-	CacheDir     string `json:"cache_dir"`
+	CacheEnabled         bool   `json:"cache_enabled"`
+	CacheExpire          int    `json:"cache_expire"`            // 缓存有效期（秒），0表示永不过期
+	CacheDir             string `json:"cache_dir"`
+	CachePolicy          string `json:"cache_policy"`            // dummy（缓存所有响应）或rfc9111
+	CacheStorage         string `json:"cache_storage"`           // filesystem 或 memory
+	CacheIgnoreHTTPCodes []int  `json:"cache_ignore_http_codes"` // 不缓存的响应状态码
+
+	// Cookie设置
+	CookiesEnabled bool   `json:"cookies_enabled"`
+	CookiesDebug   bool   `json:"cookies_debug"`
+	CookiesFile    string `json:"cookies_file"` // Netscape格式的Cookie文件，启动时导入、结束时导出
 	
 	// 自定义设置
 	Custom map[string]interface{} `json:"custom"`
@@ -61,6 +135,12 @@ type FeedExportSettings struct {
 	Headers  map[string]string `json:"headers"`
 }
 
+// DownloadSlotSettings 下载槽设置
+type DownloadSlotSettings struct {
+	Concurrency int           `json:"concurrency"`
+	Delay       time.Duration `json:"delay"`
+}
+
 // DefaultSettings 默认设置
 func DefaultSettings() *Settings {
 	numCPU := runtime.NumCPU()
@@ -75,8 +155,13 @@ func DefaultSettings() *Settings {
 	return &Settings{
 		// 基础设置
 		BotName:       "go-scrapy",
-		UserAgent:     "go-scrapy/1.0 (+https://github.com/go-scrapy/go-scrapy)",
+		UserAgent:     DefaultUserAgent,
+		UserAgentList: []string{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		},
 		RobotstxtObey: false,
+		RobotstxtFailurePolicy: "allow",
 		
 		// 并发设置
 		ConcurrentRequests:         concurrency,
@@ -87,24 +172,63 @@ func DefaultSettings() *Settings {
 		RandomizeDownloadDelay: true,
 		DownloadTimeout:       30 * time.Second,
 		
+		// 自动限速设置
+		AutoThrottleEnabled:           false,
+		AutoThrottleStartDelay:        5 * time.Second,
+		AutoThrottleMaxDelay:          60 * time.Second,
+		AutoThrottleTargetConcurrency: 1.0,
+		
+		// 关闭设置
+		ShutdownTimeout: 30 * time.Second,
+		
 		// 重试设置
 		RetryEnabled:   true,
 		RetryTimes:     3,
 		RetryHTTPCodes: []int{500, 502, 503, 504, 408, 429},
+		RetryBackoffBase: 500 * time.Millisecond,
+		RetryBackoffMax:  30 * time.Second,
+		
+		// 去重设置
+		DupeFilterEnabled: true,
+		DupeFilterBackend: "memory",
+		
+		// 调度器设置
+		Scheduler:          "channel",
+		SchedulerDiskQueue: "priority",
 		
-		// 中间件设置
+		URLLengthLimit: 2083,
+		
+		// 中间件设置（值越小越靠近引擎，负数表示不启用）
 		DownloaderMiddlewares: map[string]int{
+			"RobotsTxtMiddleware": 100,
 			"UserAgentMiddleware": 400,
-			"DelayMiddleware":     500,
+			"RetryMiddleware":     550,
+			"CookieMiddleware":    700,
+			"ProxyMiddleware":     750,
+			"HTTPCacheMiddleware": 900,
+		},
+		SpiderMiddlewares: map[string]int{
+			"RefererMiddleware":   700,
+			"URLLengthMiddleware": 800,
+			"DepthMiddleware":     900,
 		},
-		SpiderMiddlewares: map[string]int{},
 		
 		// 管道设置
 		ItemPipelines: map[string]int{
-			"ConsolePipeline": 100,
-			"JSONPipeline":    200,
+			"FeedExportPipeline": 800,
 		},
 		
+		// 扩展设置
+		Extensions: map[string]int{
+			"CoreStats":    0,
+			"LogStats":     0,
+			"MemoryUsage":  0,
+			"AutoThrottle": 0,
+		},
+		LogStatsInterval:      60 * time.Second,
+		MemUsageEnabled:       true,
+		MemUsageCheckInterval: 60 * time.Second,
+		
 		// 输出设置
 		FeedsExport: map[string]FeedExportSettings{
 			"items.json": {
@@ -122,6 +246,10 @@ func DefaultSettings() *Settings {
 		CacheEnabled: false,
 		CacheExpire:  3600,
 		CacheDir:     ".scrapy/cache",
+		CachePolicy:  "dummy",
+		CacheStorage: "filesystem",
+
+		CookiesEnabled: true,
 		
 		// 自定义设置
 		Custom: make(map[string]interface{}),
@@ -193,6 +321,17 @@ func FastSettings() *Settings {
 	return settings
 }
 
+// UserAgents 实际发出请求使用的User-Agent：显式设置了UserAgent时固定使用它，否则轮换UserAgentList
+func (s *Settings) UserAgents() []string {
+	if (s.UserAgent == "" || s.UserAgent == DefaultUserAgent) && len(s.UserAgentList) > 0 {
+		return s.UserAgentList
+	}
+	if s.UserAgent == "" {
+		return nil
+	}
+	return []string{s.UserAgent}
+}
+
 // Get 获取设置值
 func (s *Settings) Get(key string, defaultValue interface{}) interface{} {
 	switch key {
@@ -200,30 +339,100 @@ func (s *Settings) Get(key string, defaultValue interface{}) interface{} {
 		return s.BotName
 	case "USER_AGENT":
 		return s.UserAgent
+	case "USER_AGENT_LIST":
+		return s.UserAgentList
 	case "ROBOTSTXT_OBEY":
 		return s.RobotstxtObey
+	case "ROBOTSTXT_USER_AGENT":
+		return s.RobotstxtUserAgent
+	case "ROBOTSTXT_FAILURE_POLICY":
+		return s.RobotstxtFailurePolicy
 	case "CONCURRENT_REQUESTS":
 		return s.ConcurrentRequests
 	case "CONCURRENT_REQUESTS_PER_DOMAIN":
 		return s.ConcurrentRequestsPerDomain
+	case "CONCURRENT_REQUESTS_PER_IP":
+		return s.ConcurrentRequestsPerIP
+	case "DOWNLOAD_SLOTS":
+		return s.DownloadSlots
 	case "DOWNLOAD_DELAY":
 		return s.DownloadDelay
 	case "RANDOMIZE_DOWNLOAD_DELAY":
 		return s.RandomizeDownloadDelay
 	case "DOWNLOAD_TIMEOUT":
 		return s.DownloadTimeout
+	case "PROXY_LIST":
+		return s.ProxyList
+	case "AUTOTHROTTLE_ENABLED":
+		return s.AutoThrottleEnabled
+	case "AUTOTHROTTLE_START_DELAY":
+		return s.AutoThrottleStartDelay
+	case "AUTOTHROTTLE_MIN_DELAY":
+		return s.AutoThrottleMinDelay
+	case "AUTOTHROTTLE_MAX_DELAY":
+		return s.AutoThrottleMaxDelay
+	case "AUTOTHROTTLE_TARGET_CONCURRENCY":
+		return s.AutoThrottleTargetConcurrency
+	case "AUTOTHROTTLE_DEBUG":
+		return s.AutoThrottleDebug
+	case "SHUTDOWN_TIMEOUT":
+		return s.ShutdownTimeout
+	case "CLOSESPIDER_ITEMCOUNT":
+		return s.CloseSpiderItemCount
+	case "CLOSESPIDER_PAGECOUNT":
+		return s.CloseSpiderPageCount
+	case "CLOSESPIDER_ERRORCOUNT":
+		return s.CloseSpiderErrorCount
+	case "CLOSESPIDER_TIMEOUT":
+		return s.CloseSpiderTimeout
 	case "RETRY_ENABLED":
 		return s.RetryEnabled
 	case "RETRY_TIMES":
 		return s.RetryTimes
 	case "RETRY_HTTP_CODES":
 		return s.RetryHTTPCodes
+	case "RETRY_BACKOFF_BASE":
+		return s.RetryBackoffBase
+	case "RETRY_BACKOFF_MAX":
+		return s.RetryBackoffMax
+	case "DUPEFILTER_ENABLED":
+		return s.DupeFilterEnabled
+	case "DUPEFILTER_BACKEND":
+		return s.DupeFilterBackend
+	case "DUPEFILTER_PATH":
+		return s.DupeFilterPath
+	case "SCHEDULER":
+		return s.Scheduler
+	case "SCHEDULER_DISK_QUEUE":
+		return s.SchedulerDiskQueue
+	case "JOBDIR":
+		return s.JobDir
+	case "STATS_FILE":
+		return s.StatsFile
+	case "URLLENGTH_LIMIT":
+		return s.URLLengthLimit
+	case "DEPTH_LIMIT":
+		return s.DepthLimit
+	case "DEPTH_PRIORITY":
+		return s.DepthPriority
 	case "DOWNLOADER_MIDDLEWARES":
 		return s.DownloaderMiddlewares
 	case "SPIDER_MIDDLEWARES":
 		return s.SpiderMiddlewares
 	case "ITEM_PIPELINES":
 		return s.ItemPipelines
+	case "EXTENSIONS":
+		return s.Extensions
+	case "LOGSTATS_INTERVAL":
+		return s.LogStatsInterval
+	case "MEMUSAGE_ENABLED":
+		return s.MemUsageEnabled
+	case "MEMUSAGE_LIMIT_MB":
+		return s.MemUsageLimitMB
+	case "MEMUSAGE_WARNING_MB":
+		return s.MemUsageWarningMB
+	case "MEMUSAGE_CHECK_INTERVAL":
+		return s.MemUsageCheckInterval
 	case "FEEDS_EXPORT":
 		return s.FeedsExport
 	case "LOG_LEVEL":
@@ -238,6 +447,18 @@ func (s *Settings) Get(key string, defaultValue interface{}) interface{} {
 		return s.CacheExpire
 	case "CACHE_DIR":
 		return s.CacheDir
+	case "CACHE_POLICY":
+		return s.CachePolicy
+	case "CACHE_STORAGE":
+		return s.CacheStorage
+	case "CACHE_IGNORE_HTTP_CODES":
+		return s.CacheIgnoreHTTPCodes
+	case "COOKIES_ENABLED":
+		return s.CookiesEnabled
+	case "COOKIES_DEBUG":
+		return s.CookiesDebug
+	case "COOKIES_FILE":
+		return s.CookiesFile
 	default:
 		if val, exists := s.Custom[key]; exists {
 			return val
@@ -281,18 +502,56 @@ func LoadFromFile(filename string) (*Settings, error) {
 	var jsonSettings struct {
 		BotName                    string            `json:"bot_name"`
 		UserAgent                  string            `json:"user_agent"`
+		UserAgentList              []string          `json:"user_agent_list"`
 		RobotstxtObey             bool              `json:"robotstxt_obey"`
+		RobotstxtUserAgent         string            `json:"robotstxt_user_agent"`
+		RobotstxtFailurePolicy     string            `json:"robotstxt_failure_policy"`
 		ConcurrentRequests         int               `json:"concurrent_requests"`
 		ConcurrentRequestsPerDomain int              `json:"concurrent_requests_per_domain"`
+		ConcurrentRequestsPerIP    int               `json:"concurrent_requests_per_ip"`
+		DownloadSlots              map[string]struct {
+			Concurrency int    `json:"concurrency"`
+			Delay       string `json:"delay"`
+		} `json:"download_slots"`
 		DownloadDelay              string            `json:"download_delay"`
 		RandomizeDownloadDelay     bool              `json:"randomize_download_delay"`
 		DownloadTimeout            string            `json:"download_timeout"`
+		ProxyList                  []string          `json:"proxy_list"`
+		AutoThrottleEnabled        bool              `json:"autothrottle_enabled"`
+		AutoThrottleStartDelay     string            `json:"autothrottle_start_delay"`
+		AutoThrottleMinDelay       string            `json:"autothrottle_min_delay"`
+		AutoThrottleMaxDelay       string            `json:"autothrottle_max_delay"`
+		AutoThrottleTargetConcurrency float64        `json:"autothrottle_target_concurrency"`
+		AutoThrottleDebug          bool              `json:"autothrottle_debug"`
+		ShutdownTimeout            string            `json:"shutdown_timeout"`
+		CloseSpiderItemCount       int               `json:"closespider_itemcount"`
+		CloseSpiderPageCount       int               `json:"closespider_pagecount"`
+		CloseSpiderErrorCount      int               `json:"closespider_errorcount"`
+		CloseSpiderTimeout         string            `json:"closespider_timeout"`
 		RetryEnabled               bool              `json:"retry_enabled"`
 		RetryTimes                 int               `json:"retry_times"`
 		RetryHTTPCodes            []int             `json:"retry_http_codes"`
-		DownloaderMiddlewares      map[string]int    `json:"downloader_middlewares"`
-		SpiderMiddlewares          map[string]int    `json:"spider_middlewares"`
-		ItemPipelines              map[string]int    `json:"item_pipelines"`
+		RetryBackoffBase           string            `json:"retry_backoff_base"`
+		RetryBackoffMax            string            `json:"retry_backoff_max"`
+		DupeFilterEnabled          bool              `json:"dupefilter_enabled"`
+		DupeFilterBackend          string            `json:"dupefilter_backend"`
+		DupeFilterPath             string            `json:"dupefilter_path"`
+		Scheduler                  string            `json:"scheduler"`
+		SchedulerDiskQueue         string            `json:"scheduler_disk_queue"`
+		JobDir                     string            `json:"jobdir"`
+		StatsFile                  string            `json:"stats_file"`
+		URLLengthLimit             int               `json:"urllength_limit"`
+		DepthLimit                 int               `json:"depth_limit"`
+		DepthPriority              int               `json:"depth_priority"`
+		DownloaderMiddlewares      map[string]*int   `json:"downloader_middlewares"`
+		SpiderMiddlewares          map[string]*int   `json:"spider_middlewares"`
+		ItemPipelines              map[string]*int   `json:"item_pipelines"`
+		Extensions                 map[string]*int   `json:"extensions"`
+		LogStatsInterval           string            `json:"logstats_interval"`
+		MemUsageEnabled            bool              `json:"memusage_enabled"`
+		MemUsageLimitMB            int               `json:"memusage_limit_mb"`
+		MemUsageWarningMB          int               `json:"memusage_warning_mb"`
+		MemUsageCheckInterval      string            `json:"memusage_check_interval"`
 		FeedsExport               map[string]FeedExportSettings `json:"feeds_export"`
 		LogLevel                   string            `json:"log_level"`
 		LogFile                    string            `json:"log_file"`
@@ -300,6 +559,12 @@ func LoadFromFile(filename string) (*Settings, error) {
 		CacheEnabled               bool              `json:"cache_enabled"`
 		CacheExpire                int               `json:"cache_expire"`
 		CacheDir                   string            `json:"cache_dir"`
+		CachePolicy                string            `json:"cache_policy"`
+		CacheStorage               string            `json:"cache_storage"`
+		CacheIgnoreHTTPCodes       []int             `json:"cache_ignore_http_codes"`
+		CookiesEnabled             bool              `json:"cookies_enabled"`
+		CookiesDebug               bool              `json:"cookies_debug"`
+		CookiesFile                string            `json:"cookies_file"`
 		Custom                     map[string]interface{} `json:"custom"`
 	}
 	
@@ -307,19 +572,45 @@ func LoadFromFile(filename string) (*Settings, error) {
 		return nil, err
 	}
 	
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return nil, err
+	}
+	
 	settings := &Settings{
 		BotName:                    jsonSettings.BotName,
 		UserAgent:                  jsonSettings.UserAgent,
+		UserAgentList:              jsonSettings.UserAgentList,
 		RobotstxtObey:             jsonSettings.RobotstxtObey,
+		RobotstxtUserAgent:         jsonSettings.RobotstxtUserAgent,
+		RobotstxtFailurePolicy:     jsonSettings.RobotstxtFailurePolicy,
 		ConcurrentRequests:         jsonSettings.ConcurrentRequests,
 		ConcurrentRequestsPerDomain: jsonSettings.ConcurrentRequestsPerDomain,
+		ConcurrentRequestsPerIP:    jsonSettings.ConcurrentRequestsPerIP,
 		RandomizeDownloadDelay:     jsonSettings.RandomizeDownloadDelay,
+		ProxyList:                  jsonSettings.ProxyList,
+		AutoThrottleEnabled:        jsonSettings.AutoThrottleEnabled,
+		AutoThrottleTargetConcurrency: jsonSettings.AutoThrottleTargetConcurrency,
+		AutoThrottleDebug:          jsonSettings.AutoThrottleDebug,
+		CloseSpiderItemCount:       jsonSettings.CloseSpiderItemCount,
+		CloseSpiderPageCount:       jsonSettings.CloseSpiderPageCount,
+		CloseSpiderErrorCount:      jsonSettings.CloseSpiderErrorCount,
 		RetryEnabled:               jsonSettings.RetryEnabled,
 		RetryTimes:                 jsonSettings.RetryTimes,
 		RetryHTTPCodes:            jsonSettings.RetryHTTPCodes,
-		DownloaderMiddlewares:      jsonSettings.DownloaderMiddlewares,
-		SpiderMiddlewares:          jsonSettings.SpiderMiddlewares,
-		ItemPipelines:              jsonSettings.ItemPipelines,
+		DupeFilterEnabled:          jsonSettings.DupeFilterEnabled,
+		DupeFilterBackend:          jsonSettings.DupeFilterBackend,
+		DupeFilterPath:             jsonSettings.DupeFilterPath,
+		Scheduler:                  jsonSettings.Scheduler,
+		SchedulerDiskQueue:         jsonSettings.SchedulerDiskQueue,
+		JobDir:                     jsonSettings.JobDir,
+		StatsFile:                  jsonSettings.StatsFile,
+		URLLengthLimit:             jsonSettings.URLLengthLimit,
+		DepthLimit:                 jsonSettings.DepthLimit,
+		DepthPriority:              jsonSettings.DepthPriority,
+		MemUsageEnabled:            jsonSettings.MemUsageEnabled,
+		MemUsageLimitMB:            jsonSettings.MemUsageLimitMB,
+		MemUsageWarningMB:          jsonSettings.MemUsageWarningMB,
 		FeedsExport:               jsonSettings.FeedsExport,
 		LogLevel:                   jsonSettings.LogLevel,
 		LogFile:                    jsonSettings.LogFile,
@@ -327,9 +618,25 @@ func LoadFromFile(filename string) (*Settings, error) {
 		CacheEnabled:               jsonSettings.CacheEnabled,
 		CacheExpire:                jsonSettings.CacheExpire,
 		CacheDir:                   jsonSettings.CacheDir,
+		CachePolicy:                jsonSettings.CachePolicy,
+		CacheStorage:               jsonSettings.CacheStorage,
+		CacheIgnoreHTTPCodes:       jsonSettings.CacheIgnoreHTTPCodes,
+		CookiesEnabled:             jsonSettings.CookiesEnabled,
+		CookiesDebug:               jsonSettings.CookiesDebug,
+		CookiesFile:                jsonSettings.CookiesFile,
 		Custom:                     jsonSettings.Custom,
 	}
 	
+	// 文件中没有出现的键保持默认值
+	defaults := DefaultSettings()
+	fillMissingDefaults(settings, defaults, present)
+	
+	// 组件顺序表在默认值的基础上合并，null或负数表示禁用默认组件
+	settings.DownloaderMiddlewares = MergeComponentOrders(defaults.DownloaderMiddlewares, jsonSettings.DownloaderMiddlewares)
+	settings.SpiderMiddlewares = MergeComponentOrders(defaults.SpiderMiddlewares, jsonSettings.SpiderMiddlewares)
+	settings.ItemPipelines = MergeComponentOrders(defaults.ItemPipelines, jsonSettings.ItemPipelines)
+	settings.Extensions = MergeComponentOrders(defaults.Extensions, jsonSettings.Extensions)
+	
 	// 解析时间字符串
 	if jsonSettings.DownloadDelay != "" {
 		if duration, err := time.ParseDuration(jsonSettings.DownloadDelay); err == nil {
@@ -337,11 +644,104 @@ func LoadFromFile(filename string) (*Settings, error) {
 		}
 	}
 	
+	if len(jsonSettings.DownloadSlots) > 0 {
+		settings.DownloadSlots = make(map[string]DownloadSlotSettings)
+		for key, slot := range jsonSettings.DownloadSlots {
+			slotSettings := DownloadSlotSettings{Concurrency: slot.Concurrency}
+			if duration, err := time.ParseDuration(slot.Delay); err == nil {
+				slotSettings.Delay = duration
+			}
+			settings.DownloadSlots[key] = slotSettings
+		}
+	}
+	
 	if jsonSettings.DownloadTimeout != "" {
 		if duration, err := time.ParseDuration(jsonSettings.DownloadTimeout); err == nil {
 			settings.DownloadTimeout = duration
 		}
 	}
 	
+	if jsonSettings.AutoThrottleStartDelay != "" {
+		if duration, err := time.ParseDuration(jsonSettings.AutoThrottleStartDelay); err == nil {
+			settings.AutoThrottleStartDelay = duration
+		}
+	}
+	
+	if jsonSettings.AutoThrottleMinDelay != "" {
+		if duration, err := time.ParseDuration(jsonSettings.AutoThrottleMinDelay); err == nil {
+			settings.AutoThrottleMinDelay = duration
+		}
+	}
+	
+	if jsonSettings.AutoThrottleMaxDelay != "" {
+		if duration, err := time.ParseDuration(jsonSettings.AutoThrottleMaxDelay); err == nil {
+			settings.AutoThrottleMaxDelay = duration
+		}
+	}
+	
+	if jsonSettings.ShutdownTimeout != "" {
+		if duration, err := time.ParseDuration(jsonSettings.ShutdownTimeout); err == nil {
+			settings.ShutdownTimeout = duration
+		}
+	}
+	if jsonSettings.LogStatsInterval != "" {
+		if duration, err := time.ParseDuration(jsonSettings.LogStatsInterval); err == nil {
+			settings.LogStatsInterval = duration
+		}
+	}
+	if jsonSettings.MemUsageCheckInterval != "" {
+		if duration, err := time.ParseDuration(jsonSettings.MemUsageCheckInterval); err == nil {
+			settings.MemUsageCheckInterval = duration
+		}
+	}
+	if jsonSettings.CloseSpiderTimeout != "" {
+		if duration, err := time.ParseDuration(jsonSettings.CloseSpiderTimeout); err == nil {
+			settings.CloseSpiderTimeout = duration
+		}
+	}
+	
+	if jsonSettings.RetryBackoffBase != "" {
+		if duration, err := time.ParseDuration(jsonSettings.RetryBackoffBase); err == nil {
+			settings.RetryBackoffBase = duration
+		}
+	}
+	
+	if jsonSettings.RetryBackoffMax != "" {
+		if duration, err := time.ParseDuration(jsonSettings.RetryBackoffMax); err == nil {
+			settings.RetryBackoffMax = duration
+		}
+	}
+	
 	return settings, nil
-}
\ No newline at end of file
+}
+
+// fillMissingDefaults 将present中没有对应JSON键的字段设为默认值
+func fillMissingDefaults(settings, defaults *Settings, present map[string]json.RawMessage) {
+	target := reflect.ValueOf(settings).Elem()
+	source := reflect.ValueOf(defaults).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		key := strings.Split(target.Type().Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		if _, ok := present[key]; !ok {
+			target.Field(i).Set(source.Field(i))
+		}
+	}
+}
+
+// MergeComponentOrders 将组件顺序表覆盖到默认顺序表上，值为nil（JSON中的null）时禁用该组件
+func MergeComponentOrders(base map[string]int, overrides map[string]*int) map[string]int {
+	merged := make(map[string]int, len(base)+len(overrides))
+	for name, order := range base {
+		merged[name] = order
+	}
+	for name, order := range overrides {
+		if order == nil {
+			merged[name] = -1
+		} else {
+			merged[name] = *order
+		}
+	}
+	return merged
+}
@@ -0,0 +1,115 @@
+package signals
+
+import (
+	"errors"
+	"fmt"
+	"scrago/request"
+	"scrago/response"
+	"scrago/spider"
+	"sync"
+)
+
+// Signal 引擎生命周期信号
+type Signal string
+
+// 引擎发送的信号，Event中携带的字段见各信号说明
+const (
+	EngineStarted    Signal = "engine_started"    // 引擎开始运行
+	EngineStopped    Signal = "engine_stopped"    // 引擎停止运行，管道已关闭
+	SpiderOpened     Signal = "spider_opened"     // 爬虫开始：Spider
+	SpiderIdle       Signal = "spider_idle"       // 没有待处理的请求：Spider
+	SpiderClosed     Signal = "spider_closed"     // 爬虫结束：Spider、Reason
+	SpiderError      Signal = "spider_error"      // 回调出错：Spider、Response、Err
+	RequestScheduled Signal = "request_scheduled" // 请求进入调度器：Request
+	RequestDropped   Signal = "request_dropped"   // 请求被调度器或下载中间件丢弃：Request、Reason
+	ResponseReceived Signal = "response_received" // 收到响应（含缓存命中），在下载中间件处理响应之前：Request、Response
+	ItemScraped      Signal = "item_scraped"      // 数据项通过所有管道：Item
+	ItemDropped      Signal = "item_dropped"      // 数据项被管道丢弃：Item
+)
+
+// ErrDontCloseSpider spider_idle的处理函数返回该错误时爬虫不会关闭，
+// 引擎稍后再次发送spider_idle，适用于从外部队列获取请求的爬虫
+var ErrDontCloseSpider = errors.New("dont close spider")
+
+// Event 信号事件，未涉及的字段为空
+type Event struct {
+	Signal   Signal
+	Spider   spider.Spider
+	Request  *request.Request
+	Response *response.Response
+	Item     interface{}
+	Reason   string
+	Err      error
+}
+
+// Handler 信号处理函数，可能被多个协程同时调用
+type Handler func(event Event) error
+
+// Subscriber 订阅信号的组件（可选接口）
+// 引擎在运行开始时为实现该接口的爬虫、中间件和管道调用ConnectSignals
+type Subscriber interface {
+	ConnectSignals(m *Manager)
+}
+
+// Manager 信号管理器，按连接顺序同步调用处理函数
+type Manager struct {
+	handlers map[Signal][]Handler
+	mutex    sync.RWMutex
+}
+
+// NewManager 创建信号管理器
+func NewManager() *Manager {
+	return &Manager{
+		handlers: make(map[Signal][]Handler),
+	}
+}
+
+// Connect 订阅信号
+func (m *Manager) Connect(signal Signal, handler Handler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers[signal] = append(m.handlers[signal], handler)
+}
+
+// DisconnectAll 取消信号的所有订阅
+func (m *Manager) DisconnectAll(signal Signal) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.handlers, signal)
+}
+
+// HasHandlers 信号是否有处理函数
+func (m *Manager) HasHandlers(signal Signal) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.handlers[signal]) > 0
+}
+
+// Send 发送信号，依次调用所有处理函数。处理函数的错误和panic不会中断其他处理函数，
+// 除ErrDontCloseSpider外都会打印出来，返回合并后的错误
+func (m *Manager) Send(event Event) error {
+	m.mutex.RLock()
+	handlers := m.handlers[event.Signal]
+	m.mutex.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := callHandler(handler, event); err != nil {
+			if !errors.Is(err, ErrDontCloseSpider) {
+				fmt.Printf("⚠️  信号 %s 处理出错: %v\n", event.Signal, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// callHandler 调用处理函数，将panic转换为错误
+func callHandler(handler Handler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("signal handler panic: %v", r)
+		}
+	}()
+	return handler(event)
+}
@@ -0,0 +1,75 @@
+package spider
+
+import (
+	"reflect"
+	"scrago/request"
+	"scrago/response"
+	"strings"
+	"unicode"
+)
+
+// CallbackResolver 可按名称提供回调的爬虫（可选接口）
+// 返回nil时回退到按方法名反射查找
+type CallbackResolver interface {
+	Callback(name string) response.Callback
+}
+
+// ErrbackResolver 可按名称提供错误回调的爬虫（可选接口）
+type ErrbackResolver interface {
+	Errback(name string) response.Errback
+}
+
+// LookupCallback 按名称查找爬虫的回调方法，如 "parseDetail" 对应 ParseDetail
+func LookupCallback(s Spider, name string) response.Callback {
+	if name == "" {
+		return nil
+	}
+
+	if resolver, ok := s.(CallbackResolver); ok {
+		if cb := resolver.Callback(name); cb != nil {
+			return cb
+		}
+	}
+
+	method := lookupMethod(s, name)
+	if !method.IsValid() {
+		return nil
+	}
+
+	cb, _ := method.Interface().(func(*response.Response) []interface{})
+	return cb
+}
+
+// LookupErrback 按名称查找爬虫的错误回调方法
+func LookupErrback(s Spider, name string) response.Errback {
+	if name == "" {
+		return nil
+	}
+
+	if resolver, ok := s.(ErrbackResolver); ok {
+		if eb := resolver.Errback(name); eb != nil {
+			return eb
+		}
+	}
+
+	method := lookupMethod(s, name)
+	if !method.IsValid() {
+		return nil
+	}
+
+	eb, _ := method.Interface().(func(*request.Request, error) []interface{})
+	return eb
+}
+
+// lookupMethod 反射查找导出方法，名称首字母自动转为大写
+func lookupMethod(s Spider, name string) reflect.Value {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return reflect.Value{}
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return reflect.ValueOf(s).MethodByName(string(runes))
+}
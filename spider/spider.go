@@ -3,6 +3,7 @@ package spider
 import (
 	"scrago/request"
 	"scrago/response"
+	"scrago/stats"
 )
 
 // Spider 爬虫接口
@@ -12,11 +13,18 @@ type Spider interface {
 	Parse(resp *response.Response) []interface{}
 }
 
+// AllowedDomainsProvider 限定爬取域名的爬虫（可选接口）
+// 引擎会丢弃回调产生的、主机不在这些域名（含子域名）内的请求
+type AllowedDomainsProvider interface {
+	AllowedDomains() []string
+}
+
 // BaseSpider 基础爬虫实现
 type BaseSpider struct {
 	name       string
 	startUrls  []string
 	allowedDomains []string
+	stats      stats.Collector
 }
 
 // NewBaseSpider 创建基础爬虫
@@ -27,11 +35,44 @@ func NewBaseSpider(name string, startUrls []string) *BaseSpider {
 	}
 }
 
+// NewBaseSpiderWithDomains 创建限定爬取域名的基础爬虫
+func NewBaseSpiderWithDomains(name string, startUrls []string, allowedDomains []string) *BaseSpider {
+	return &BaseSpider{
+		name:           name,
+		startUrls:      startUrls,
+		allowedDomains: allowedDomains,
+	}
+}
+
 // Name 返回爬虫名称
 func (s *BaseSpider) Name() string {
 	return s.name
 }
 
+// AllowedDomains 返回允许爬取的域名
+func (s *BaseSpider) AllowedDomains() []string {
+	return s.allowedDomains
+}
+
+// SetAllowedDomains 设置允许爬取的域名（含子域名）
+func (s *BaseSpider) SetAllowedDomains(domains ...string) *BaseSpider {
+	s.allowedDomains = domains
+	return s
+}
+
+// SetStatsCollector 由引擎在运行开始时设置统计收集器
+func (s *BaseSpider) SetStatsCollector(c stats.Collector) {
+	s.stats = c
+}
+
+// StatsCollector 返回统计收集器，用于记录自定义计数，未运行时返回空收集器
+func (s *BaseSpider) StatsCollector() stats.Collector {
+	if s.stats == nil {
+		return stats.NewDummyCollector()
+	}
+	return s.stats
+}
+
 // StartRequests 生成初始请求
 func (s *BaseSpider) StartRequests() []*request.Request {
 	requests := make([]*request.Request, 0, len(s.startUrls))
@@ -0,0 +1,107 @@
+package spiderloader
+
+import (
+	"fmt"
+	"scrago/settings"
+	"scrago/spider"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory 按设置创建爬虫实例
+type Factory func(s *settings.Settings) spider.Spider
+
+// Info 爬虫注册信息
+type Info struct {
+	Name           string                     // 爬虫名称，scrago crawl <name>
+	Aliases        []string                   // 别名
+	Description    string                     // 描述，scrago list 中显示
+	AllowedDomains []string                   // 允许爬取的域名，爬虫自身未设置时使用
+	CustomSettings func(s *settings.Settings) // 爬虫自定义设置，在配置文件之后、命令行 -s 之前应用
+	New            Factory
+}
+
+// 全局注册表
+var (
+	spiders = make(map[string]Info)
+	aliases = make(map[string]string)
+	mutex   sync.RWMutex
+)
+
+// Register 注册爬虫，通常在爬虫文件的init()中调用；名称为空或重复时panic
+func Register(info Info) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if info.Name == "" {
+		panic("spiderloader: Register spider with empty name")
+	}
+	if info.New == nil {
+		panic("spiderloader: Register spider " + info.Name + " with nil factory")
+	}
+
+	names := append([]string{info.Name}, info.Aliases...)
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, dup := aliases[key]; dup {
+			panic("spiderloader: Register called twice for spider " + name)
+		}
+	}
+	for _, name := range names {
+		aliases[strings.ToLower(name)] = info.Name
+	}
+	spiders[info.Name] = info
+}
+
+// Lookup 按名称或别名（不区分大小写）查找爬虫
+func Lookup(name string) (Info, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	key, ok := aliases[strings.ToLower(name)]
+	if !ok {
+		return Info{}, false
+	}
+	return spiders[key], true
+}
+
+// List 返回所有已注册的爬虫（按名称排序）
+func List() []Info {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	infos := make([]Info, 0, len(spiders))
+	for _, info := range spiders {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Load 按名称创建爬虫
+//
+// 爬虫自身没有设置允许的域名时使用注册信息中的AllowedDomains。
+func Load(name string, s *settings.Settings) (spider.Spider, error) {
+	info, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown spider: %s", name)
+	}
+
+	sp := info.New(s)
+	if sp == nil {
+		return nil, fmt.Errorf("spider %s factory returned nil", info.Name)
+	}
+
+	if len(info.AllowedDomains) > 0 {
+		if base, ok := sp.(interface {
+			AllowedDomains() []string
+			SetAllowedDomains(domains ...string) *spider.BaseSpider
+		}); ok && len(base.AllowedDomains()) == 0 {
+			base.SetAllowedDomains(info.AllowedDomains...)
+		}
+	}
+	return sp, nil
+}
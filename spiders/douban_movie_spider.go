@@ -1,13 +1,13 @@
 package spiders
 
 import (
-	"encoding/json"
 	"fmt"
 	"scrago/request"
 	"scrago/response"
 	"scrago/selector"
 	"scrago/settings"
 	"scrago/spider"
+	"scrago/spiderloader"
 	"regexp"
 	"strconv"
 	"strings"
@@ -167,6 +167,22 @@ type DoubanMovieSpider struct {
 	settings *settings.Settings
 }
 
+func init() {
+	spiderloader.Register(spiderloader.Info{
+		Name:           "douban_movie",
+		Aliases:        []string{"douban"},
+		Description:    "豆瓣电影爬虫：抓取热门电影列表及详情",
+		AllowedDomains: []string{"douban.com"},
+		CustomSettings: func(s *settings.Settings) {
+			// 豆瓣会拦截非浏览器的User-Agent
+			s.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+		},
+		New: func(s *settings.Settings) spider.Spider {
+			return NewDoubanMovieSpider(s)
+		},
+	})
+}
+
 // NewDoubanMovieSpider 创建豆瓣电影爬虫
 func NewDoubanMovieSpider(settings *settings.Settings) *DoubanMovieSpider {
 	// 起始URL - 豆瓣电影API
@@ -174,7 +190,7 @@ func NewDoubanMovieSpider(settings *settings.Settings) *DoubanMovieSpider {
 		"https://movie.douban.com/j/search_subjects?type=movie&tag=热门&sort=recommend&page_limit=20&page_start=0",
 	}
 
-	base := spider.NewBaseSpider("douban_movie_spider", startURLs)
+	base := spider.NewBaseSpiderWithDomains("douban_movie_spider", startURLs, []string{"douban.com"})
 
 	return &DoubanMovieSpider{
 		BaseSpider: base,
@@ -191,9 +207,8 @@ func (s *DoubanMovieSpider) StartRequests() []*request.Request {
 	// 生成多页请求
 	for start := 0; start < 60; start += 20 {
 		url := fmt.Sprintf("%s?type=movie&tag=热门&sort=recommend&page_limit=20&page_start=%d", baseURL, start)
-		req := request.NewRequest("GET", url)
+		req, _ := request.NewJSONRequest("GET", url, nil) // 没有请求体时不会出错
 		s.setAPIHeaders(req)
-		req.SetMeta("callback", "parse")
 		requests = append(requests, req)
 	}
 
@@ -201,10 +216,9 @@ func (s *DoubanMovieSpider) StartRequests() []*request.Request {
 	return requests
 }
 
-// setAPIHeaders 设置API请求头
+// setAPIHeaders 设置API请求头，Accept由NewJSONRequest设置
 func (s *DoubanMovieSpider) setAPIHeaders(req *request.Request) {
 	req.SetHeader("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.SetHeader("Accept", "application/json, text/plain, */*")
 	req.SetHeader("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.SetHeader("Accept-Encoding", "gzip, deflate, br")
 	req.SetHeader("Referer", "https://movie.douban.com/")
@@ -228,16 +242,6 @@ func (s *DoubanMovieSpider) Parse(resp *response.Response) []interface{} {
 		return []interface{}{}
 	}
 
-	// 检查是否是详情页请求
-	if callback, exists := resp.Meta["callback"]; exists && callback == "parseMovieDetail" {
-		return s.ParseMovieDetail(resp)
-	}
-
-	// 检查URL是否是详情页
-	if strings.Contains(resp.URL, "/subject/") {
-		return s.ParseMovieDetail(resp)
-	}
-
 	// 解析API响应
 	var apiResponse struct {
 		Subjects []struct {
@@ -251,7 +255,7 @@ func (s *DoubanMovieSpider) Parse(resp *response.Response) []interface{} {
 		} `json:"subjects"`
 	}
 
-	if err := json.Unmarshal(resp.Body, &apiResponse); err != nil {
+	if err := resp.JSONInto(&apiResponse); err != nil {
 		fmt.Printf("❌ JSON解析失败: %v\n", err)
 		fmt.Printf("🔍 响应URL: %s\n", resp.URL)
 		if len(resp.Body) > 100 {
@@ -275,7 +279,7 @@ func (s *DoubanMovieSpider) Parse(resp *response.Response) []interface{} {
 		// 创建详情页请求
 		detailReq := request.NewRequest("GET", subject.URL)
 		s.setDetailHeaders(detailReq)
-		detailReq.SetMeta("callback", "parseMovieDetail")
+		detailReq.SetCallback(s.ParseMovieDetail)
 		detailReq.SetMeta("movie_id", subject.ID)
 		detailReq.SetMeta("basic_info", map[string]interface{}{
 			"id":       subject.ID,
@@ -317,7 +321,7 @@ func (s *DoubanMovieSpider) ParseMovieDetail(resp *response.Response) []interfac
 	}
 
 	// 从HTML中提取详细信息
-	movie.ExtractFromHTML(string(resp.Body))
+	movie.ExtractFromHTML(resp.Text())
 
 	// 验证数据有效性
 	if !movie.IsValid() {
@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Collector 统计收集器，引擎、爬虫、中间件和管道共用
+//
+// 计数类的值为int64，Set可以记录任意可序列化为JSON的值（如时间、关闭原因）。
+type Collector interface {
+	// Get 获取统计值，不存在时返回nil
+	Get(key string) interface{}
+	// GetInt 获取计数值，不存在或不是计数时返回0
+	GetInt(key string) int64
+	// Set 设置统计值
+	Set(key string, value interface{})
+	// Inc 计数增加delta
+	Inc(key string, delta int64)
+	// Max 记录最大值
+	Max(key string, value int64)
+	// Min 记录最小值
+	Min(key string, value int64)
+	// All 返回所有统计值的副本
+	All() map[string]interface{}
+}
+
+// CollectorAware 需要使用统计收集器的组件（可选接口）
+// 引擎在运行开始时为实现该接口的爬虫、中间件和管道设置收集器
+type CollectorAware interface {
+	SetStatsCollector(c Collector)
+}
+
+// MemoryCollector 内存统计收集器，并发安全
+type MemoryCollector struct {
+	values map[string]interface{}
+	mutex  sync.RWMutex
+}
+
+// NewMemoryCollector 创建内存统计收集器
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{
+		values: make(map[string]interface{}),
+	}
+}
+
+// Get 获取统计值
+func (c *MemoryCollector) Get(key string) interface{} {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.values[key]
+}
+
+// GetInt 获取计数值
+func (c *MemoryCollector) GetInt(key string) int64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	value, _ := c.values[key].(int64)
+	return value
+}
+
+// Set 设置统计值
+func (c *MemoryCollector) Set(key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] = value
+}
+
+// Inc 计数增加delta，原值不是计数时从0开始
+func (c *MemoryCollector) Inc(key string, delta int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, _ := c.values[key].(int64)
+	c.values[key] = value + delta
+}
+
+// Max 记录最大值
+func (c *MemoryCollector) Max(key string, value int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if current, ok := c.values[key].(int64); !ok || value > current {
+		c.values[key] = value
+	}
+}
+
+// Min 记录最小值
+func (c *MemoryCollector) Min(key string, value int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if current, ok := c.values[key].(int64); !ok || value < current {
+		c.values[key] = value
+	}
+}
+
+// All 返回所有统计值的副本
+func (c *MemoryCollector) All() map[string]interface{} {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	values := make(map[string]interface{}, len(c.values))
+	for key, value := range c.values {
+		values[key] = value
+	}
+	return values
+}
+
+// DummyCollector 不记录任何统计的收集器，用于关闭统计以减少开销
+type DummyCollector struct{}
+
+// NewDummyCollector 创建空统计收集器
+func NewDummyCollector() *DummyCollector {
+	return &DummyCollector{}
+}
+
+func (c *DummyCollector) Get(key string) interface{}        { return nil }
+func (c *DummyCollector) GetInt(key string) int64           { return 0 }
+func (c *DummyCollector) Set(key string, value interface{}) {}
+func (c *DummyCollector) Inc(key string, delta int64)       {}
+func (c *DummyCollector) Max(key string, value int64)       {}
+func (c *DummyCollector) Min(key string, value int64)       {}
+func (c *DummyCollector) All() map[string]interface{}       { return map[string]interface{}{} }
+
+// SortedKeys 按字母顺序返回统计键
+func SortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Print 按键的字母顺序打印所有统计值
+func Print(c Collector) {
+	values := c.All()
+	for _, key := range SortedKeys(values) {
+		fmt.Printf("%s: %v\n", key, values[key])
+	}
+}
+
+// WriteJSON 将所有统计值写入JSON文件，先写临时文件再重命名
+func WriteJSON(c Collector, path string) error {
+	data, err := json.MarshalIndent(c.All(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}